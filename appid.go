@@ -0,0 +1,161 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"net/url"
+)
+
+const (
+	appIDIdentifier        = "appid"
+	appIDExcludeIdentifier = "appidExclude"
+)
+
+// AppIDExtension requests the appid extension, see WithAppID.
+type AppIDExtension struct {
+	AppID string
+}
+
+// AppIDExcludeExtension requests the appidExclude extension, see WithAppIDExclude.
+type AppIDExcludeExtension struct {
+	AppID string
+}
+
+// WithAppID requests the appid extension, so credentials registered with the legacy U2F API
+// under the AppID URL can still be used. Login only.
+func WithAppID(appID string) Option {
+	return WithExtension(&AppIDExtension{AppID: appID})
+}
+
+// WithAppIDExclude requests the appidExclude extension, so authenticators holding a legacy U2F credential
+// from the exclude list (registered under the AppID URL) are rejected too. Registration only.
+func WithAppIDExclude(appID string) Option {
+	return WithExtension(&AppIDExcludeExtension{AppID: appID})
+}
+
+// Identifier returns "appid".
+func (e *AppIDExtension) Identifier() string { return appIDIdentifier }
+
+// AddInput adds the AppID.
+func (e *AppIDExtension) AddInput(c *CeremonyOptions) error {
+	if c.Ceremony != CeremonyAuthentication {
+		return fmt.Errorf("%w: appid", ErrOptionNotApplicable)
+	}
+	if err := validateAppID(e.AppID); err != nil {
+		return err
+	}
+	return c.SetExtensionInput(appIDIdentifier, e.AppID)
+}
+
+// ValidateAuthenticatorOutput does nothing, appid is a client-only extension.
+func (e *AppIDExtension) ValidateAuthenticatorOutput(Ceremony, *AuthenticatorExtensionOutputs, *Extensions) error {
+	return nil
+}
+
+// ValidateClientOutput records whether the assertion was scoped to the AppID.
+func (e *AppIDExtension) ValidateClientOutput(_ Ceremony, output json.RawMessage, result *Extensions) error {
+	if output == nil {
+		return nil
+	}
+	return decodeExtensionOutput(appIDIdentifier, output, &result.AppID)
+}
+
+func decodeAppIDExtension(ceremony Ceremony, inputs ExtensionInputs) (Extension, error) {
+	ext := &AppIDExtension{}
+	if ok, err := decodeExtensionInput(inputs, appIDIdentifier, &ext.AppID); !ok || ceremony != CeremonyAuthentication {
+		return nil, err
+	}
+	return ext, nil
+}
+
+// Identifier returns "appidExclude".
+func (e *AppIDExcludeExtension) Identifier() string { return appIDExcludeIdentifier }
+
+// AddInput adds the AppID.
+func (e *AppIDExcludeExtension) AddInput(c *CeremonyOptions) error {
+	if c.Ceremony != CeremonyRegistration {
+		return fmt.Errorf("%w: appidExclude", ErrOptionNotApplicable)
+	}
+	if err := validateAppID(e.AppID); err != nil {
+		return err
+	}
+	return c.SetExtensionInput(appIDExcludeIdentifier, e.AppID)
+}
+
+// ValidateAuthenticatorOutput does nothing, appidExclude is a client-only extension.
+func (e *AppIDExcludeExtension) ValidateAuthenticatorOutput(Ceremony, *AuthenticatorExtensionOutputs, *Extensions) error {
+	return nil
+}
+
+// ValidateClientOutput records whether the client checked the exclude list against the AppID.
+func (e *AppIDExcludeExtension) ValidateClientOutput(_ Ceremony, output json.RawMessage, result *Extensions) error {
+	if output == nil {
+		return nil
+	}
+	return decodeExtensionOutput(appIDExcludeIdentifier, output, &result.AppIDExclude)
+}
+
+func decodeAppIDExcludeExtension(ceremony Ceremony, inputs ExtensionInputs) (Extension, error) {
+	ext := &AppIDExcludeExtension{}
+	if ok, err := decodeExtensionInput(inputs, appIDExcludeIdentifier, &ext.AppID); !ok || ceremony != CeremonyRegistration {
+		return nil, err
+	}
+	return ext, nil
+}
+
+// WithExcludeCredentials lists base64url encoded credential IDs the user already has,
+// so the same authenticator isn't registered twice. Registration only.
+func WithExcludeCredentials(credentialIDs ...string) Option {
+	return func(c *CeremonyOptions) error {
+		if c.Ceremony != CeremonyRegistration {
+			return fmt.Errorf("%w: excludeCredentials", ErrOptionNotApplicable)
+		}
+		for _, credID := range credentialIDs {
+			c.Registration.ExcludeCredentials = append(c.Registration.ExcludeCredentials, PublicKeyCredentialDescriptor{
+				Type: "public-key",
+				ID:   credID,
+			})
+		}
+		return nil
+	}
+}
+
+// validateAppID checks that the AppID is an absolute HTTPS URL, as U2F requires.
+func validateAppID(appID string) error {
+	u, err := url.Parse(appID)
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", ErrInvalidAppID, appID, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w %s: must be an absolute HTTPS URL", ErrInvalidAppID, appID)
+	}
+	return nil
+}
+
+// expectedLoginRPID returns the RP ID the assertion must be scoped to. If the client reports
+// that the appid extension was used, it's the requested AppID instead of Config.RPID.
+// The appid output is only accepted if the extension was requested, so the input is present then.
+func (w *WebAuthn) expectedLoginRPID(c *LoginData, extensions Extensions) (string, error) {
+	if !extensions.AppID {
+		return w.Config.RPID, nil
+	}
+	var appID string
+	if _, err := decodeExtensionInput(c.Options.Extensions, appIDIdentifier, &appID); err != nil {
+		return "", err
+	}
+	return appID, nil
+}
+
+// U2FPublicKeyToCOSE converts a legacy U2F public key (uncompressed P-256 point, 65 bytes)
+// into the COSE format expected in LoginData.PublicKey.
+func U2FPublicKeyToCOSE(rawKey []byte) ([]byte, error) {
+	key, err := webauthncose.ParseFIDOPublicKey(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPublicKey, err)
+	}
+	key.KeyType = int64(webauthncose.EllipticKey)
+	key.Curve = int64(webauthncose.P256)
+	return webauthncbor.Marshal(key)
+}
@@ -4,11 +4,12 @@ import (
 	"encoding/binary"
 	"fmt"
 	"github.com/MrBoombastic/WebAuthn2Go/aaguid"
+	"github.com/MrBoombastic/WebAuthn2Go/cose"
+	"github.com/fxamacker/cbor/v2"
 	"github.com/google/uuid"
 	"log"
 
 	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
-	"github.com/go-webauthn/webauthn/protocol/webauthncose"
 )
 
 // ParsedAuthData holds the structured information from the authenticator data.
@@ -64,15 +65,20 @@ func (w *WebAuthn) ParseAuthenticatorData(authDataBytes []byte) (*ParsedAuthData
 
 		// Credential Public Key (COSE format) follows the credential ID
 		credentialKeyBytes := authDataBytes[currentOffset:]
-		//var keyBytesRead int // Use int to match NumBytesRead() //we probably don't need this, at least not yet
 
 		if len(credentialKeyBytes) > 0 {
-			_, err := webauthncose.ParsePublicKey(credentialKeyBytes)
-			if err != nil && parsed.Flags&0x80 == 0 {
+			// Extension data may follow the key, so find out where the key ends
+			var rawKey cbor.RawMessage
+			rest, err := cbor.UnmarshalFirst(credentialKeyBytes, &rawKey)
+			if err != nil {
 				return nil, ErrParsingCOSEKey
 			}
-			parsed.CredentialPubKeyBytes = credentialKeyBytes
-			//currentOffset += keyBytesRead // as above, we are not using it eight now, but may in the future
+			keyBytesRead := len(credentialKeyBytes) - len(rest)
+			if _, err := cose.ParsePublicKey(credentialKeyBytes[:keyBytesRead]); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrParsingCOSEKey, err)
+			}
+			parsed.CredentialPubKeyBytes = credentialKeyBytes[:keyBytesRead]
+			currentOffset += keyBytesRead
 		} else if parsed.Flags&0x80 == 0 {
 			// AT flag set, but no bytes remain for public key, and ED not set.
 			return nil, ErrATFlagButNoData
@@ -4,19 +4,62 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/MrBoombastic/WebAuthn2Go/utils"
+	"strings"
 )
 
+// credentialTypePublicKey is the only credential type defined by WebAuthn.
+const credentialTypePublicKey = "public-key"
+
+// PublicKeyCredential is the registration response of the browser. Parse accepts both the standard
+// RegistrationResponseJSON (PublicKeyCredential.toJSON(), with the attestation nested under "response")
+// and the legacy flat form with clientDataJSON and attestationObject at the top level.
 type PublicKeyCredential struct {
-	ID                string `json:"id"`
-	AttestationObject string `json:"attestationObject"`
-	ClientDataJSON    string `json:"clientDataJSON"`
-	clientData        ClientData
+	ID                      string                  `json:"id"`
+	RawID                   string                  `json:"rawId,omitempty"`
+	Type                    string                  `json:"type,omitempty"`
+	AuthenticatorAttachment AuthenticatorAttachment `json:"authenticatorAttachment,omitempty"`
+	AttestationObject       string                  `json:"attestationObject"`
+	ClientDataJSON          string                  `json:"clientDataJSON"`
+	ClientExtensionResults  ClientExtensionResults  `json:"clientExtensionResults"`
+	// Optional members of the attestation response, passed on with RegistrationData
+	Transports         []AuthenticatorTransport `json:"transports,omitempty"`
+	PublicKeyAlgorithm *int64                   `json:"publicKeyAlgorithm,omitempty"`
+	PublicKey          string                   `json:"publicKey,omitempty"` // Base64url DER SubjectPublicKeyInfo
+	clientData         ClientData
+}
+
+// authenticatorAttestationResponseJSON is the "response" member of RegistrationResponseJSON.
+type authenticatorAttestationResponseJSON struct {
+	ClientDataJSON     string                   `json:"clientDataJSON"`
+	AttestationObject  string                   `json:"attestationObject"`
+	Transports         []AuthenticatorTransport `json:"transports"`
+	PublicKeyAlgorithm *int64                   `json:"publicKeyAlgorithm"`
+	PublicKey          string                   `json:"publicKey"`
 }
 
 func (pkc *PublicKeyCredential) Parse(data []byte) (err error) {
-	if err := json.Unmarshal(data, pkc); err != nil {
+	var payload struct {
+		PublicKeyCredential
+		Response *authenticatorAttestationResponseJSON `json:"response"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
 		return fmt.Errorf("%w: %w", ErrFailedUnmarshalPublicKeyCredential, err)
 	}
+	*pkc = payload.PublicKeyCredential
+	if payload.Response != nil {
+		pkc.ClientDataJSON = payload.Response.ClientDataJSON
+		pkc.AttestationObject = payload.Response.AttestationObject
+		pkc.Transports = payload.Response.Transports
+		pkc.PublicKeyAlgorithm = payload.Response.PublicKeyAlgorithm
+		pkc.PublicKey = payload.Response.PublicKey
+	}
+	if err := checkCredentialIdentity(pkc.ID, pkc.RawID, pkc.Type); err != nil {
+		return err
+	}
+	pkc.Transports = knownTransports(pkc.Transports)
+	if pkc.AuthenticatorAttachment != "" && !pkc.AuthenticatorAttachment.IsValid() {
+		return fmt.Errorf("%w: %s", ErrInvalidAuthenticatorAttachment, pkc.AuthenticatorAttachment)
+	}
 	b, err := utils.DecodeBase64URL(pkc.ClientDataJSON)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrFailedDecodeClientData, err)
@@ -31,21 +74,63 @@ func (pkc *PublicKeyCredential) ClientData() (c *ClientData) {
 	return &pkc.clientData
 }
 
+// RegistrationData returns the inputs for FinishRegistration, with the options returned by BeginRegistration (may be nil).
+func (pkc *PublicKeyCredential) RegistrationData(options *BeginRegistrationOptions) RegistrationData {
+	return RegistrationData{
+		ClientDataJSON:          pkc.ClientDataJSON,
+		AttestationObject:       pkc.AttestationObject,
+		ClientExtensionResults:  pkc.ClientExtensionResults,
+		Options:                 options,
+		Transports:              pkc.Transports,
+		AuthenticatorAttachment: pkc.AuthenticatorAttachment,
+		PublicKeyAlgorithm:      pkc.PublicKeyAlgorithm,
+		PublicKeySPKI:           pkc.PublicKey,
+	}
+}
+
+// PublicKeyCredentialAssertion is the login response of the browser. Parse accepts both the standard
+// AuthenticationResponseJSON (PublicKeyCredential.toJSON(), with the assertion nested under "response")
+// and the legacy flat form.
 type PublicKeyCredentialAssertion struct {
 	// Matches PublicKeyCredential structure from client Assertion
-	ID                string `json:"id"`
-	Type              string `json:"type"`
-	AuthenticatorData string `json:"authenticatorData"`
+	ID                      string                  `json:"id"`
+	RawID                   string                  `json:"rawId,omitempty"`
+	Type                    string                  `json:"type"`
+	AuthenticatorAttachment AuthenticatorAttachment `json:"authenticatorAttachment,omitempty"`
+	AuthenticatorData       string                  `json:"authenticatorData"`
+	ClientDataJSON          string                  `json:"clientDataJSON"`
+	clientData              ClientData
+	Signature               string                 `json:"signature"`
+	UserHandle              string                 `json:"userHandle"`
+	ClientExtensionResults  ClientExtensionResults `json:"clientExtensionResults"`
+}
+
+// authenticatorAssertionResponseJSON is the "response" member of AuthenticationResponseJSON.
+type authenticatorAssertionResponseJSON struct {
 	ClientDataJSON    string `json:"clientDataJSON"`
-	clientData        ClientData
+	AuthenticatorData string `json:"authenticatorData"`
 	Signature         string `json:"signature"`
 	UserHandle        string `json:"userHandle"`
 }
 
 func (p *PublicKeyCredentialAssertion) Parse(data []byte) (err error) {
-	if err := json.Unmarshal(data, p); err != nil {
+	var payload struct {
+		PublicKeyCredentialAssertion
+		Response *authenticatorAssertionResponseJSON `json:"response"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
 		return fmt.Errorf("%w: %w", ErrFailedUnmarshalPublicKeyCredentialAssertion, err)
 	}
+	*p = payload.PublicKeyCredentialAssertion
+	if payload.Response != nil {
+		p.ClientDataJSON = payload.Response.ClientDataJSON
+		p.AuthenticatorData = payload.Response.AuthenticatorData
+		p.Signature = payload.Response.Signature
+		p.UserHandle = payload.Response.UserHandle
+	}
+	if err := checkCredentialIdentity(p.ID, p.RawID, p.Type); err != nil {
+		return err
+	}
 	b, err := utils.DecodeBase64URL(p.ClientDataJSON)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrFailedDecodeClientData, err)
@@ -59,3 +144,14 @@ func (p *PublicKeyCredentialAssertion) Parse(data []byte) (err error) {
 func (p *PublicKeyCredentialAssertion) GetChallenge() string {
 	return p.clientData.Challenge
 }
+
+// checkCredentialIdentity checks the optional type and rawId members against the credential ID.
+func checkCredentialIdentity(id, rawID, credType string) error {
+	if credType != "" && credType != credentialTypePublicKey {
+		return fmt.Errorf("%w: %s", ErrInvalidCredentialType, credType)
+	}
+	if rawID != "" && strings.TrimRight(rawID, "=") != strings.TrimRight(id, "=") {
+		return ErrRawIDMismatch
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package webauthn
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultMaxConditionalChallenges bounds the in-memory store created by New.
+const defaultMaxConditionalChallenges = 10_000
+
+// ChallengeStore keeps user-agnostic challenges issued by BeginConditionalLogin until they are used or expire.
+// Implement it on top of a shared backend (e.g., Redis) if you run several instances of your server.
+type ChallengeStore interface {
+	// Save stores the challenge until expiresAt.
+	Save(challenge string, expiresAt time.Time) error
+	// Consume removes the challenge and reports whether it existed and had not expired yet.
+	Consume(challenge string) (bool, error)
+}
+
+// MemoryChallengeStore is an in-memory ChallengeStore holding at most a fixed number of challenges.
+// When it's full, the oldest challenge is evicted, so pages left open forever can't grow it without bound.
+type MemoryChallengeStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]time.Time
+	queue      []string // Challenges in insertion order, possibly including already consumed ones
+}
+
+// NewMemoryChallengeStore creates an in-memory store for at most maxEntries challenges.
+func NewMemoryChallengeStore(maxEntries int) *MemoryChallengeStore {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxConditionalChallenges
+	}
+	return &MemoryChallengeStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]time.Time),
+	}
+}
+
+// Save stores the challenge, dropping expired ones and evicting the oldest one if the store is full.
+func (s *MemoryChallengeStore) Save(challenge string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for len(s.queue) > 0 {
+		head := s.queue[0]
+		exp, ok := s.entries[head]
+		if ok && now.Before(exp) && len(s.entries) < s.maxEntries {
+			break
+		}
+		delete(s.entries, head)
+		s.queue = s.queue[1:]
+	}
+	// Consumed challenges linger in the queue until they reach its head, so compact it from time to time
+	if len(s.queue) > 2*s.maxEntries {
+		compacted := make([]string, 0, len(s.entries))
+		for _, c := range s.queue {
+			if _, ok := s.entries[c]; ok {
+				compacted = append(compacted, c)
+			}
+		}
+		s.queue = compacted
+	}
+
+	s.entries[challenge] = expiresAt
+	s.queue = append(s.queue, challenge)
+	return nil
+}
+
+// Consume removes the challenge, returning false if it's unknown or expired.
+func (s *MemoryChallengeStore) Consume(challenge string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exp, ok := s.entries[challenge]
+	if !ok {
+		return false, nil
+	}
+	delete(s.entries, challenge)
+	return time.Now().Before(exp), nil
+}
@@ -0,0 +1,145 @@
+package webauthn
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/MrBoombastic/WebAuthn2Go/cose"
+)
+
+// algRS1 is the COSE algorithm RSASSA-PKCS1-v1_5 w/ SHA-1 (RFC 8812), still used by some TPM attestations.
+const algRS1 = -65535
+
+// ComplianceProfile restricts the cryptography ceremonies accept, for deployments that must show only approved
+// algorithms are used. Set it in Config.Compliance. Results report its Name.
+type ComplianceProfile struct {
+	Name string // e.g., "FIPS 140-3"
+	// Algorithms allowed at registration and login. pubKeyCredParams only offer those, and New rejects
+	// Config.Algorithms outside of them.
+	Algorithms []cose.Algorithm
+	// MinRSABits is the minimum RSA modulus size, on top of cose.MinRSABits.
+	MinRSABits int
+	// RejectSHA1Certificates refuses attestation statements signed with RS1 (RSASSA-PKCS1-v1_5 w/ SHA-1), or with
+	// certificates signed using SHA-1 (or MD5).
+	RejectSHA1Certificates bool
+}
+
+// ProfileFIPS1403 returns a profile allowing the FIPS 186-5 signature algorithms with at least 2048-bit RSA keys
+// (NIST SP 800-131A), and refusing SHA-1 attestations. Every call returns a new profile, so changing one doesn't
+// affect other instances.
+func ProfileFIPS1403() *ComplianceProfile {
+	return &ComplianceProfile{
+		Name: "FIPS 140-3",
+		Algorithms: []cose.Algorithm{
+			cose.AlgES256,
+			cose.AlgES384,
+			cose.AlgES512,
+			cose.AlgEdDSA,
+			cose.AlgPS256,
+			cose.AlgRS256,
+		},
+		MinRSABits:             2048,
+		RejectSHA1Certificates: true,
+	}
+}
+
+// validate checks the profile and the configured algorithms against it.
+func (p *ComplianceProfile) validate(algorithms []cose.Algorithm) error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidComplianceProfile)
+	}
+	if len(p.Algorithms) == 0 {
+		return fmt.Errorf("%w: no algorithms", ErrInvalidComplianceProfile)
+	}
+	if err := validateAlgorithms(p.Algorithms); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidComplianceProfile, err)
+	}
+	for _, alg := range algorithms {
+		if !p.allows(alg) {
+			return fmt.Errorf("%w: %s by %s", ErrAlgorithmNotAllowed, alg, p.Name)
+		}
+	}
+	return nil
+}
+
+// allows checks if the profile allows the algorithm.
+func (p *ComplianceProfile) allows(alg cose.Algorithm) bool {
+	for _, allowed := range p.Algorithms {
+		if allowed == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// complianceName returns the name of the active compliance profile, empty without one.
+func (w *WebAuthn) complianceName() string {
+	if w.Config.Compliance == nil {
+		return ""
+	}
+	return w.Config.Compliance.Name
+}
+
+// checkOfferedAlgorithms checks that the compliance profile allows every offered algorithm.
+func (w *WebAuthn) checkOfferedAlgorithms(params []CredentialParameter) error {
+	profile := w.Config.Compliance
+	if profile == nil {
+		return nil
+	}
+	for _, param := range params {
+		if !profile.allows(cose.Algorithm(param.Alg)) {
+			return fmt.Errorf("%w: %s by %s", ErrAlgorithmNotAllowed, cose.Algorithm(param.Alg), profile.Name)
+		}
+	}
+	return nil
+}
+
+// checkKeyCompliance checks the credential public key against the compliance profile, if any.
+func (w *WebAuthn) checkKeyCompliance(key cose.PublicKey) error {
+	profile := w.Config.Compliance
+	if profile == nil {
+		return nil
+	}
+	if !profile.allows(key.Algorithm()) {
+		return fmt.Errorf("%w: %s by %s", ErrAlgorithmNotAllowed, key.Algorithm(), profile.Name)
+	}
+	if rsaKey, ok := key.(*cose.RSAPublicKey); ok && rsaKey.Bits() < profile.MinRSABits {
+		return fmt.Errorf("%w: %d bits, %s requires %d", ErrRSAKeyTooSmall, rsaKey.Bits(), profile.Name, profile.MinRSABits)
+	}
+	return nil
+}
+
+// checkAttestationStatement refuses RS1 attestation signatures and x5c certificates signed with SHA-1 if the
+// compliance profile requires it. The attestation statement isn't verified otherwise.
+func (w *WebAuthn) checkAttestationStatement(attStmt map[string]interface{}) error {
+	profile := w.Config.Compliance
+	if profile == nil || !profile.RejectSHA1Certificates {
+		return nil
+	}
+	// Negative CBOR integers decode as int64
+	if alg, ok := attStmt["alg"].(int64); ok && alg == algRS1 {
+		return fmt.Errorf("%w: RS1 by %s", ErrWeakAttestationAlgorithm, profile.Name)
+	}
+	x5c, ok := attStmt["x5c"]
+	if !ok {
+		return nil
+	}
+	certs, ok := x5c.([]interface{})
+	if !ok {
+		return fmt.Errorf("%w: x5c is not an array", ErrInvalidAttestationCertificate)
+	}
+	for i, raw := range certs {
+		der, ok := raw.([]byte)
+		if !ok {
+			return fmt.Errorf("%w: x5c[%d] is not a byte string", ErrInvalidAttestationCertificate, i)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("%w: x5c[%d]: %w", ErrInvalidAttestationCertificate, i, err)
+		}
+		switch cert.SignatureAlgorithm {
+		case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1, x509.MD5WithRSA, x509.MD2WithRSA:
+			return fmt.Errorf("%w: x5c[%d] is signed with %s", ErrWeakAttestationCertificate, i, cert.SignatureAlgorithm)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package webauthn
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/MrBoombastic/WebAuthn2Go/utils"
+	"time"
+)
+
+// MediationConditional requests the browser's passkey autofill UI.
+const MediationConditional = "conditional"
+
+// defaultConditionalTimeout is used if Config.ConditionalTimeout is not set (milliseconds, 30 minutes).
+const defaultConditionalTimeout uint32 = 30 * 60 * 1000
+
+// CredentialRequestOptions holds the whole argument for navigator.credentials.get(),
+// as conditional mediation is requested outside the publicKey member.
+type CredentialRequestOptions struct {
+	Mediation string                            `json:"mediation"`
+	PublicKey PublicKeyCredentialRequestOptions `json:"publicKey"`
+}
+
+// StoredCredential holds a credential as persisted by the caller after registration.
+type StoredCredential struct {
+	UserID       []byte // User handle (UserEntity.ID) of the owner
+	CredentialID string // Base64url encoded credential ID
+	PublicKey    []byte
+	SignCount    uint32
+}
+
+// CredentialResolver looks up a stored credential during usernameless login.
+// credentialID is base64url encoded, userHandle is nil if the authenticator didn't return it.
+type CredentialResolver func(credentialID string, userHandle []byte) (*StoredCredential, error)
+
+// conditionalTimeout returns the configured lifetime of conditional challenges.
+func (w *WebAuthn) conditionalTimeout() uint32 {
+	if w.Config.ConditionalTimeout > 0 {
+		return w.Config.ConditionalTimeout
+	}
+	return defaultConditionalTimeout
+}
+
+// BeginConditionalLogin generates options for passkey autofill (conditional mediation).
+// They carry no allow list, and the challenge isn't tied to any user - it's kept in
+// Config.ConditionalChallenges until FinishConditionalLogin consumes it or it expires.
+func (w *WebAuthn) BeginConditionalLogin(opts ...Option) (*CredentialRequestOptions, error) {
+	if w == nil {
+		return nil, ErrNilInstance
+	}
+	if w.Config.ConditionalChallenges == nil {
+		return nil, ErrNilChallengeStore
+	}
+	challenge, err := utils.GenerateChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGeneratingChallenge, err)
+	}
+
+	timeout := w.conditionalTimeout()
+	options := &CredentialRequestOptions{
+		Mediation: MediationConditional,
+		PublicKey: PublicKeyCredentialRequestOptions{
+			Challenge:        challenge,
+			Timeout:          timeout,
+			RPID:             w.Config.RPID,
+			AllowCredentials: []PublicKeyCredentialDescriptor{},
+			UserVerification: w.Config.UserVerification,
+		},
+	}
+	if err := applyLoginOptions(&options.PublicKey, opts); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	if err := w.Config.ConditionalChallenges.Save(challenge, expiresAt); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSavingChallenge, err)
+	}
+	return options, nil
+}
+
+// FinishConditionalLogin completes a login started with BeginConditionalLogin.
+// The user is resolved from data.CredentialID and data.UserHandle using resolve,
+// so data.PublicKey and data.StoredSignCount don't have to be set by the caller.
+func (w *WebAuthn) FinishConditionalLogin(data *LoginData, resolve CredentialResolver) (*LoginResult, error) {
+	if w == nil {
+		return nil, ErrNilInstance
+	}
+	if data == nil {
+		return nil, ErrNilLoginData
+	}
+	if resolve == nil {
+		return nil, ErrNilCredentialResolver
+	}
+	if data.CredentialID == "" {
+		return nil, ErrMissingCredentialID
+	}
+	if w.Config.ConditionalChallenges == nil {
+		return nil, ErrNilChallengeStore
+	}
+
+	var clientData ClientData
+	if _, err := clientData.ParseWithB64(data.ClientDataJSON); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailedParseClientData, err)
+	}
+	// Consume the challenge first, so it can't be replayed even if verification fails
+	ok, err := w.Config.ConditionalChallenges.Consume(clientData.Challenge)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConsumingChallenge, err)
+	}
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+
+	var userHandle []byte
+	if data.UserHandle != "" {
+		if userHandle, err = utils.DecodeBase64URL(data.UserHandle); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFailedDecodeUserHandle, err)
+		}
+	}
+	cred, err := resolve(data.CredentialID, userHandle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCredentialNotFound, err)
+	}
+	if cred == nil {
+		return nil, ErrCredentialNotFound
+	}
+	// The credential must belong to the user the authenticator claims
+	if cred.CredentialID != data.CredentialID {
+		return nil, ErrCredentialNotFound
+	}
+	if userHandle != nil && subtle.ConstantTimeCompare(userHandle, cred.UserID) == 0 {
+		return nil, ErrUserHandleMismatch
+	}
+
+	resolved := *data
+	resolved.PublicKey = cred.PublicKey
+	resolved.StoredSignCount = cred.SignCount
+	resolved.UserID = cred.UserID
+	res, err := w.FinishLogin(&resolved)
+	if err != nil {
+		return nil, err
+	}
+	res.CredentialID = cred.CredentialID
+	res.UserID = cred.UserID
+	return res, nil
+}
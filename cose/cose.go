@@ -0,0 +1,211 @@
+// Package cose parses COSE_Key credential public keys (RFC 9052, RFC 9053) as found in WebAuthn authenticator data
+// into typed public keys, verifies signatures with them and exports them as JWK or PEM.
+//
+// Parsing is strict: the key must be a definite-length CBOR map without duplicate labels or trailing data, the algorithm must match
+// the key type and curve, EC points must be on their curve and RSA moduli must be within MinRSABits and MaxRSABits.
+// ParseOptions.Lenient relaxes the encoding and size checks for keys that were stored before.
+package cose
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Algorithm is a COSE algorithm identifier.
+type Algorithm int64
+
+// Signature algorithms supported by this package
+const (
+	AlgES256 Algorithm = -7   // ECDSA w/ SHA-256 on P-256
+	AlgEdDSA Algorithm = -8   // EdDSA on Ed25519
+	AlgES384 Algorithm = -35  // ECDSA w/ SHA-384 on P-384
+	AlgES512 Algorithm = -36  // ECDSA w/ SHA-512 on P-521
+	AlgPS256 Algorithm = -37  // RSASSA-PSS w/ SHA-256
+	AlgRS256 Algorithm = -257 // RSASSA-PKCS1-v1_5 w/ SHA-256
+)
+
+// IsValid checks if the algorithm is one of the defined constants.
+func (a Algorithm) IsValid() bool {
+	switch a {
+	case AlgES256, AlgEdDSA, AlgES384, AlgES512, AlgPS256, AlgRS256:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns the JOSE name of the algorithm, e.g., "ES256".
+func (a Algorithm) String() string {
+	switch a {
+	case AlgES256:
+		return "ES256"
+	case AlgEdDSA:
+		return "EdDSA"
+	case AlgES384:
+		return "ES384"
+	case AlgES512:
+		return "ES512"
+	case AlgPS256:
+		return "PS256"
+	case AlgRS256:
+		return "RS256"
+	default:
+		return fmt.Sprintf("COSE(%d)", int64(a))
+	}
+}
+
+// KeyType is a COSE key type.
+type KeyType int64
+
+const (
+	KeyTypeOKP KeyType = 1 // Octet key pair (Ed25519)
+	KeyTypeEC2 KeyType = 2 // Elliptic curve with x and y coordinates
+	KeyTypeRSA KeyType = 3
+)
+
+// Curve is a COSE elliptic curve identifier.
+type Curve int64
+
+const (
+	CurveP256    Curve = 1
+	CurveP384    Curve = 2
+	CurveP521    Curve = 3
+	CurveEd25519 Curve = 6
+)
+
+// String returns the JOSE name of the curve, e.g., "P-256".
+func (c Curve) String() string {
+	switch c {
+	case CurveP256:
+		return "P-256"
+	case CurveP384:
+		return "P-384"
+	case CurveP521:
+		return "P-521"
+	case CurveEd25519:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("COSE(%d)", int64(c))
+	}
+}
+
+// RSA modulus size limits (bits)
+const (
+	MinRSABits = 2048
+	MaxRSABits = 8192
+)
+
+// PublicKey is a parsed credential public key.
+type PublicKey interface {
+	// Algorithm returns the signature algorithm the key is used with.
+	Algorithm() Algorithm
+	// KeyType returns the COSE key type.
+	KeyType() KeyType
+	// CryptoPublicKey returns the key as *ecdsa.PublicKey, *rsa.PublicKey or ed25519.PublicKey.
+	CryptoPublicKey() crypto.PublicKey
+	// Verify checks a WebAuthn signature over data (authenticatorData || clientDataHash).
+	Verify(data, signature []byte) error
+	// JWK returns the key as a JSON Web Key.
+	JWK() JWK
+}
+
+// COSE_Key labels
+const (
+	labelKty = 1
+	labelAlg = 3
+	labelCrv = -1 // Also n for RSA
+	labelX   = -2 // Also e for RSA
+	labelY   = -3
+)
+
+// decMode rejects duplicate labels and indefinite-length items, which have no place in a credential key.
+var decMode, _ = cbor.DecOptions{
+	DupMapKey:       cbor.DupMapKeyEnforcedAPF,
+	IndefLength:     cbor.IndefLengthForbidden,
+	MaxNestedLevels: 4,
+}.DecMode()
+
+// lenientDecMode accepts what decMode rejects.
+var lenientDecMode, _ = cbor.DecOptions{
+	MaxNestedLevels: 4,
+}.DecMode()
+
+// ParseOptions configures ParsePublicKeyWithOptions. The zero value parses as strictly as ParsePublicKey.
+type ParseOptions struct {
+	// Lenient skips the encoding and size checks: duplicate labels, indefinite-length items, trailing data, EC
+	// coordinates shorter than the curve size, non-minimal RSA integers, even RSA moduli and MinRSABits and MaxRSABits.
+	// Use it to verify signatures with keys stored before these checks existed, never to accept new keys.
+	Lenient bool
+}
+
+// parser holds the decoding settings of one parse.
+type parser struct {
+	decMode cbor.DecMode
+	lenient bool
+}
+
+// ParsePublicKey parses and validates a COSE_Key.
+func ParsePublicKey(data []byte) (PublicKey, error) {
+	return ParsePublicKeyWithOptions(data, ParseOptions{})
+}
+
+// ParsePublicKeyWithOptions parses a COSE_Key, as strictly as opts asks for.
+func ParsePublicKeyWithOptions(data []byte, opts ParseOptions) (PublicKey, error) {
+	p := parser{decMode: decMode}
+	var params map[int64]cbor.RawMessage
+	var err error
+	if opts.Lenient {
+		p = parser{decMode: lenientDecMode, lenient: true}
+		_, err = lenientDecMode.UnmarshalFirst(data, &params)
+	} else {
+		err = decMode.Unmarshal(data, &params)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedKey, err)
+	}
+	var kty, alg int64
+	if err := p.intParam(params, labelKty, &kty); err != nil {
+		return nil, err
+	}
+	if err := p.intParam(params, labelAlg, &alg); err != nil {
+		return nil, err
+	}
+
+	switch KeyType(kty) {
+	case KeyTypeEC2:
+		return p.parseEC2(Algorithm(alg), params)
+	case KeyTypeRSA:
+		return p.parseRSA(Algorithm(alg), params)
+	case KeyTypeOKP:
+		return p.parseOKP(Algorithm(alg), params)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedKeyType, kty)
+	}
+}
+
+// intParam decodes a required integer parameter.
+func (p parser) intParam(params map[int64]cbor.RawMessage, label int64, v *int64) error {
+	raw, ok := params[label]
+	if !ok {
+		return fmt.Errorf("%w: missing parameter %d", ErrMalformedKey, label)
+	}
+	if err := p.decMode.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: parameter %d: %w", ErrMalformedKey, label, err)
+	}
+	return nil
+}
+
+// bytesParam decodes a required byte string parameter.
+func (p parser) bytesParam(params map[int64]cbor.RawMessage, label int64) ([]byte, error) {
+	raw, ok := params[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing parameter %d", ErrMalformedKey, label)
+	}
+	var b []byte
+	if err := p.decMode.Unmarshal(raw, &b); err != nil {
+		return nil, fmt.Errorf("%w: parameter %d: %w", ErrMalformedKey, label, err)
+	}
+	return b, nil
+}
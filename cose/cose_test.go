@@ -0,0 +1,284 @@
+package cose
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	// RFC 8032 section 7.1, test 1: an empty message
+	ed25519X         = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	ed25519Signature = "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46b" +
+		"d25bf5f0595bbe24655141438e7a100b"
+	// RFC 6979 appendix A.2.5, SHA-256 over "sample" (r and s DER encoded)
+	p256X         = "60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6"
+	p256Y         = "7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299"
+	p256Signature = "3046022100efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716022100f7cb1c942d657c41" +
+		"d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8"
+	// Generated with crypto/ecdsa and crypto/rsa, over "sample"
+	p384X         = "aa03579f0b840809afa4e7f0646e4a6b3d83fdbd6ff8d4d3e4b4520e82e1080a29eee6800d4b8d536e4d0bf157a6b569"
+	p384Y         = "11b3c1ad67b0df6292d2bc8280bf31c2a5b3d1679c7cae1e57452fa19c6635cc3148fba56a63d4228d563e724dfb9a5a"
+	p384Signature = "3066023100b7bdb97f705f20851b6ff68247081b673043af0b78ea59aec45876d7f9c7d4314a2e1deca87c7b9a2a8914" +
+		"3dd94aa4b2023100a509bd4b32b51638615699030c85c0650262bb8c1ab0fae6de9b97b17c5eb52d7947a64d13938d25" +
+		"70fe866159fa4129"
+	p521X = "0084b8a1d0ec7064e1152f0ec75113bd3af3f0b5320597a3389cd8f8b95441ba4eb709588cc1833a42fbfc86f4f7d7cd" +
+		"326cae6f824b301ab5e5469a4291a43de99f"
+	p521Y = "017cc33ff4c56fa02993f8b28f3a89d43005bc38f735f98392806739a6d9f0850baae58818871f12893f3792d1681429" +
+		"31710a163bfe137bf5707e1d1eb0e3ba82ab"
+	p521Signature = "3081870242010a559c3c2b61cdf50d52ed5dc1a29b97cc2e59525a4f5bc1a14e5b77f76a74cc51fb2657e529a188b4af" +
+		"7bd61b887d068684eb5e58c0621456613f9da98ee0be65024179b9620ee09c264c1d1e60c14c0b7fd97cdba8b659f8a6" +
+		"e14ed1e1832a9c30e2511d8536ffc91714bc7f1768b85f49d0e8635c56971a7f7c4dc224d714253d85e0"
+	rsaN = "c00bf9db7f81e333b796ba436278e84078e21f31894695ed0339b933bc637bd16a0f6a83f8f2d9b9782a603c6ec50413" +
+		"b407e087d38580ee0d5fef0a07c153ce6c7ad3041915bce035ce6f3c5638e610b3fd67ed2f92793f59be6061d4649fa5" +
+		"bdcd94a0008e8e491abe026a275feb35e9f879c51536158b4a9ad0b65d9c7f3874e93bf330009110f8f89950440c90c8" +
+		"66c66d5fc68ee32c0fbe09ebac0306c93cb9ca8bc0124697b00fb63d759cf87f918114ba093b5380316e81b40aa0d0a0" +
+		"c0323252c7b088516a1524b2232bc13993dab77598da3caa524a58f17d7d08f2c4d0ee3d65dce028364acc3a57d9d7cc" +
+		"2d27c712a489ec101aa4a0b2e43e7301"
+	ps256Signature = "af1d5c5052f2d8997a8fa04d4214c5f288c557571831b4fcf51f0086a4e909a931b6eb653fedb24ed4a9f3cb470f21a1" +
+		"a51e9e916495524029977d1daa8ef569d98a6b1afbfe0f069dc3ade23f641ddecd4b49ed9ac5ee9280e6c18d57c4c09c" +
+		"a4cc727a406830d75fd77f21ca90f26db1549669abcd939af5bac198c19062371aeea5d031a8e6d0644768b420c21839" +
+		"6211d36d32c4c3a3eaa9b526253a4c4d6669f55f9391b8abe1009bad410bb214b0700f41272b3d68f39e8c92b336fa15" +
+		"4832e91cd2e3efad79074cc0d320f4b63af469256b06fb659f444219ae81cffd84bf75be3e919c8888cfac01cdb67c5b" +
+		"16475b93d3461cdfb9fa35b7735d29cb"
+	rs256Signature = "261ae603b8cb119b9bf117cc7acb8c3483c0cae98569e14c377621db6686c62f8ed4be1a562759a3845066bd2ca7e83b" +
+		"c40c584afda8f4f3175b297fa2b62f6b8eca937041483ca4707edb17a20c39be0b55018f5a4bac379876b83ca6063e3f" +
+		"131d7bb651a6618a100e0e008a9f3ea87802db63be46db94eaca9dd23364d59a11f1c9493aaf081f56bce84a2ddb39e6" +
+		"fe452eae5a6ed021a621801c11e433ed9b8e3536e968b75da792938ead6cb487acc53fb3d5ff88dd7799836d03c58e38" +
+		"8a7faac09fc1dc3091c923ec3101777fca092c72453488cdbbf116c90c59cdbc87892b35813def7f1663313a22f96e77" +
+		"fa2d0379856932ddd4ff587b9a4c5b29"
+)
+
+var sample = []byte("sample")
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("DecodeString: %v", err)
+	}
+	return b
+}
+
+func encode(t *testing.T, params map[int64]any) []byte {
+	t.Helper()
+	data, err := cbor.Marshal(params)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return data
+}
+
+func ec2Key(t *testing.T, alg Algorithm, crv Curve, x, y string) []byte {
+	t.Helper()
+	return encode(t, map[int64]any{labelKty: KeyTypeEC2, labelAlg: alg, labelCrv: crv, labelX: unhex(t, x), labelY: unhex(t, y)})
+}
+
+func rsaKey(t *testing.T, alg Algorithm, n []byte) []byte {
+	t.Helper()
+	return encode(t, map[int64]any{labelKty: KeyTypeRSA, labelAlg: alg, labelCrv: n, labelX: []byte{1, 0, 1}})
+}
+
+func okpKey(t *testing.T, alg Algorithm, crv Curve, x string) []byte {
+	t.Helper()
+	return encode(t, map[int64]any{labelKty: KeyTypeOKP, labelAlg: alg, labelCrv: crv, labelX: unhex(t, x)})
+}
+
+// modulus returns an odd modulus of the given size, only its size and parity are checked while parsing.
+func modulus(bits int) []byte {
+	n := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	return n.SetBit(n, 0, 1).Bytes()
+}
+
+func TestParsePublicKey(t *testing.T) {
+	// y + 1 isn't on P-256
+	offCurveY := new(big.Int).Add(new(big.Int).SetBytes(unhex(t, p256Y)), big.NewInt(1))
+	evenModulus := modulus(MinRSABits)
+	evenModulus[len(evenModulus)-1] = 0
+	zero := strings.Repeat("00", 32)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"ES256", ec2Key(t, AlgES256, CurveP256, p256X, p256Y), nil},
+		{"ES384", ec2Key(t, AlgES384, CurveP384, p384X, p384Y), nil},
+		{"ES512", ec2Key(t, AlgES512, CurveP521, p521X, p521Y), nil},
+		{"PS256", rsaKey(t, AlgPS256, unhex(t, rsaN)), nil},
+		{"RS256", rsaKey(t, AlgRS256, unhex(t, rsaN)), nil},
+		{"EdDSA", okpKey(t, AlgEdDSA, CurveEd25519, ed25519X), nil},
+		{"RSA at the minimum size", rsaKey(t, AlgRS256, modulus(MinRSABits)), nil},
+		{"RSA at the maximum size", rsaKey(t, AlgRS256, modulus(MaxRSABits)), nil},
+
+		{"off-curve point", ec2Key(t, AlgES256, CurveP256, p256X, hex.EncodeToString(offCurveY.FillBytes(make([]byte, 32)))), ErrInvalidPoint},
+		{"point at infinity", ec2Key(t, AlgES256, CurveP256, zero, zero), ErrInvalidPoint},
+		{"P-384 point on P-256", ec2Key(t, AlgES256, CurveP256, p384X[:64], p384Y[:64]), ErrInvalidPoint},
+		{"short coordinates", ec2Key(t, AlgES256, CurveP256, p256X[2:], p256Y), ErrMalformedKey},
+		{"RSA below the minimum size", rsaKey(t, AlgRS256, modulus(MinRSABits-1)), ErrRSAKeySize},
+		{"RSA 1024 bits", rsaKey(t, AlgRS256, modulus(1024)), ErrRSAKeySize},
+		{"RSA above the maximum size", rsaKey(t, AlgRS256, modulus(MaxRSABits+1)), ErrRSAKeySize},
+		{"RSA even modulus", rsaKey(t, AlgRS256, evenModulus), ErrInvalidRSAKey},
+		{"RSA modulus with a leading zero", rsaKey(t, AlgRS256, append([]byte{0}, unhex(t, rsaN)...)), ErrInvalidRSAKey},
+		{"RSA even exponent", encode(t, map[int64]any{labelKty: KeyTypeRSA, labelAlg: AlgRS256, labelCrv: unhex(t, rsaN), labelX: []byte{1, 0, 0}}), ErrInvalidRSAKey},
+		{"ES384 on P-256", ec2Key(t, AlgES384, CurveP256, p256X, p256Y), ErrAlgorithmKeyMismatch},
+		{"ES256 on P-521", ec2Key(t, AlgES256, CurveP521, p521X, p521Y), ErrAlgorithmKeyMismatch},
+		{"EdDSA on P-256", ec2Key(t, AlgEdDSA, CurveP256, p256X, p256Y), ErrAlgorithmKeyMismatch},
+		{"ES256 with RSA", rsaKey(t, AlgES256, unhex(t, rsaN)), ErrAlgorithmKeyMismatch},
+		{"ES256 on Ed25519", okpKey(t, AlgES256, CurveEd25519, ed25519X), ErrAlgorithmKeyMismatch},
+		{"Ed25519 on EC2", ec2Key(t, AlgEdDSA, CurveEd25519, p256X, p256Y), ErrUnsupportedCurve},
+		{"X25519", okpKey(t, AlgEdDSA, 4, ed25519X), ErrUnsupportedCurve},
+		{"symmetric key", encode(t, map[int64]any{labelKty: 4, labelAlg: AlgES256}), ErrUnsupportedKeyType},
+		{"missing algorithm", encode(t, map[int64]any{labelKty: KeyTypeEC2, labelCrv: CurveP256}), ErrMalformedKey},
+		{"text label value", encode(t, map[int64]any{labelKty: "EC2", labelAlg: AlgES256}), ErrMalformedKey},
+		// {1: 1, 3: -8, 1: 2}
+		{"duplicate label", unhex(t, "a3010103270102"), ErrMalformedKey},
+		// {_ 1: 1}, indefinite length
+		{"indefinite length map", unhex(t, "bf0101ff"), ErrMalformedKey},
+		{"trailing data", append(okpKey(t, AlgEdDSA, CurveEd25519, ed25519X), 0), ErrMalformedKey},
+		{"not a map", unhex(t, "80"), ErrMalformedKey},
+		{"empty", nil, ErrMalformedKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePublicKey(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParsePublicKey() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && key.CryptoPublicKey() == nil {
+				t.Fatalf("ParsePublicKey() returned no crypto key")
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       []byte
+		signature string
+	}{
+		{"ES256", ec2Key(t, AlgES256, CurveP256, p256X, p256Y), p256Signature},
+		{"ES384", ec2Key(t, AlgES384, CurveP384, p384X, p384Y), p384Signature},
+		{"ES512", ec2Key(t, AlgES512, CurveP521, p521X, p521Y), p521Signature},
+		{"PS256", rsaKey(t, AlgPS256, unhex(t, rsaN)), ps256Signature},
+		{"RS256", rsaKey(t, AlgRS256, unhex(t, rsaN)), rs256Signature},
+		{"EdDSA", okpKey(t, AlgEdDSA, CurveEd25519, ed25519X), ed25519Signature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePublicKey(tt.key)
+			if err != nil {
+				t.Fatalf("ParsePublicKey: %v", err)
+			}
+			data := sample
+			if key.Algorithm() == AlgEdDSA {
+				data = nil
+			}
+			signature := unhex(t, tt.signature)
+			if err := key.Verify(data, signature); err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if err := key.Verify(append(data, 0), signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() of other data error = %v, want %v", err, ErrInvalidSignature)
+			}
+			signature[len(signature)-1] ^= 1
+			if err := key.Verify(data, signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() of a modified signature error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+// A PS256 signature must not pass as RS256 with the same key, and the other way around.
+func TestVerifyRSAPadding(t *testing.T) {
+	ps, err := ParsePublicKey(rsaKey(t, AlgPS256, unhex(t, rsaN)))
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	rs, err := ParsePublicKey(rsaKey(t, AlgRS256, unhex(t, rsaN)))
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if err := ps.Verify(sample, unhex(t, rs256Signature)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("PS256 Verify() of an RS256 signature error = %v, want %v", err, ErrInvalidSignature)
+	}
+	if err := rs.Verify(sample, unhex(t, ps256Signature)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("RS256 Verify() of a PS256 signature error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestJWK(t *testing.T) {
+	tests := []struct {
+		name string
+		key  []byte
+		want JWK
+	}{
+		// RFC 8037 appendix A.2
+		{"EdDSA", okpKey(t, AlgEdDSA, CurveEd25519, ed25519X), JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo", Alg: "EdDSA"}},
+		{"ES256", ec2Key(t, AlgES256, CurveP256, p256X, p256Y), JWK{
+			Kty: "EC", Crv: "P-256", Alg: "ES256",
+			X: "YP7UuiVanTHJYet0xjVtaMBJuJI7Yfps5mliLmDyn7Y",
+			Y: "eQP-EAi4vJmkGunpVii8ZPLxsgwtfp9Rd6PClNRGIpk",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePublicKey(tt.key)
+			if err != nil {
+				t.Fatalf("ParsePublicKey: %v", err)
+			}
+			if got := key.JWK(); got != tt.want {
+				t.Fatalf("JWK() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePublicKeyLenient(t *testing.T) {
+	okp := okpKey(t, AlgEdDSA, CurveEd25519, ed25519X)
+	// The P-521 x coordinate starts with a zero byte
+	shortX := encode(t, map[int64]any{labelKty: KeyTypeEC2, labelAlg: AlgES512, labelCrv: CurveP521, labelX: unhex(t, p521X[2:]), labelY: unhex(t, p521Y)})
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error // Lenient error, strict parsing fails in all cases
+	}{
+		{"trailing data", append(okp, 0), nil},
+		// {_ 1: 1, 3: -8, -1: 6, -2: x}, indefinite length
+		{"indefinite length map", append(append(unhex(t, "bf010103272006215820"), unhex(t, ed25519X)...), 0xff), nil},
+		// {1: 1, 3: -8, -1: 6, -2: x, 1: 1}
+		{"duplicate label", append(append(unhex(t, "a5010103272006215820"), unhex(t, ed25519X)...), 0x01, 0x01), nil},
+		{"stripped EC coordinate", shortX, nil},
+		{"RSA 1024 bits", rsaKey(t, AlgRS256, modulus(1024)), nil},
+		{"RSA modulus with a leading zero", rsaKey(t, AlgRS256, append([]byte{0}, unhex(t, rsaN)...)), nil},
+		{"off-curve point", ec2Key(t, AlgES256, CurveP256, p256X, p384Y[:64]), ErrInvalidPoint},
+		{"ES384 on P-256", ec2Key(t, AlgES384, CurveP256, p256X, p256Y), ErrAlgorithmKeyMismatch},
+		{"RSA exponent out of range", encode(t, map[int64]any{labelKty: KeyTypeRSA, labelAlg: AlgRS256, labelCrv: unhex(t, rsaN), labelX: []byte{1}}), ErrInvalidRSAKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePublicKey(tt.data); err == nil {
+				t.Fatalf("ParsePublicKey() accepted the key")
+			}
+			_, err := ParsePublicKeyWithOptions(tt.data, ParseOptions{Lenient: true})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParsePublicKeyWithOptions() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	// Stripped coordinates are padded back, so signatures still verify
+	key, err := ParsePublicKeyWithOptions(shortX, ParseOptions{Lenient: true})
+	if err != nil {
+		t.Fatalf("ParsePublicKeyWithOptions: %v", err)
+	}
+	if err := key.Verify(sample, unhex(t, p521Signature)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package cose
+
+import "errors"
+
+var (
+	ErrMalformedKey         = errors.New("malformed COSE key")
+	ErrUnsupportedKeyType   = errors.New("unsupported COSE key type")
+	ErrUnsupportedAlgorithm = errors.New("unsupported COSE algorithm")
+	ErrUnsupportedCurve     = errors.New("unsupported COSE curve")
+	ErrAlgorithmKeyMismatch = errors.New("COSE algorithm doesn't match the key")
+	ErrInvalidPoint         = errors.New("EC point is not on the curve")
+	ErrInvalidRSAKey        = errors.New("invalid RSA key")
+	ErrRSAKeySize           = errors.New("RSA modulus size out of range")
+	ErrInvalidSignature     = errors.New("invalid signature")
+)
@@ -0,0 +1,48 @@
+package cose
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+)
+
+// JWK is a public JSON Web Key (RFC 7517, RFC 8037).
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+func (k *EC2PublicKey) JWK() JWK {
+	return JWK{Kty: "EC", Crv: k.Curve.String(), X: b64(k.X), Y: b64(k.Y), Alg: k.Alg.String()}
+}
+
+func (k *RSAPublicKey) JWK() JWK {
+	return JWK{Kty: "RSA", N: b64(k.N), E: b64(k.E), Alg: k.Alg.String()}
+}
+
+func (k *OKPPublicKey) JWK() JWK {
+	return JWK{Kty: "OKP", Crv: k.Curve.String(), X: b64(k.X), Alg: k.Alg.String()}
+}
+
+// MarshalPKIX returns the key as a DER encoded SubjectPublicKeyInfo.
+func MarshalPKIX(key PublicKey) ([]byte, error) {
+	return x509.MarshalPKIXPublicKey(key.CryptoPublicKey())
+}
+
+// MarshalPEM returns the key as a PEM encoded "PUBLIC KEY" block.
+func MarshalPEM(key PublicKey) ([]byte, error) {
+	der, err := MarshalPKIX(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
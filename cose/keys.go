@@ -0,0 +1,233 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // Hashes used by Verify
+	_ "crypto/sha512"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// EC2PublicKey is an ECDSA key on P-256, P-384 or P-521.
+type EC2PublicKey struct {
+	Alg   Algorithm
+	Curve Curve
+	X, Y  []byte // Big-endian, padded to the curve size
+	key   *ecdsa.PublicKey
+}
+
+// RSAPublicKey is an RSA key used with RS256 or PS256.
+type RSAPublicKey struct {
+	Alg Algorithm
+	N   []byte // Big-endian modulus
+	E   []byte // Big-endian exponent
+	key *rsa.PublicKey
+}
+
+// OKPPublicKey is an Ed25519 key.
+type OKPPublicKey struct {
+	Alg   Algorithm
+	Curve Curve
+	X     []byte
+}
+
+// ecCurves maps the COSE curves to their ECDSA algorithm, curve and coordinate size.
+var ecCurves = map[Curve]struct {
+	alg   Algorithm
+	curve elliptic.Curve
+	size  int
+}{
+	CurveP256: {AlgES256, elliptic.P256(), 32},
+	CurveP384: {AlgES384, elliptic.P384(), 48},
+	CurveP521: {AlgES512, elliptic.P521(), 66},
+}
+
+func (p parser) parseEC2(alg Algorithm, params map[int64]cbor.RawMessage) (*EC2PublicKey, error) {
+	var crv int64
+	if err := p.intParam(params, labelCrv, &crv); err != nil {
+		return nil, err
+	}
+	spec, ok := ecCurves[Curve(crv)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedCurve, crv)
+	}
+	if alg != spec.alg {
+		return nil, fmt.Errorf("%w: %s on %s", ErrAlgorithmKeyMismatch, alg, Curve(crv))
+	}
+	x, err := p.bytesParam(params, labelX)
+	if err != nil {
+		return nil, err
+	}
+	y, err := p.bytesParam(params, labelY)
+	if err != nil {
+		return nil, err
+	}
+	if p.lenient && len(x) <= spec.size && len(y) <= spec.size {
+		// Some encoders strip leading zeros
+		x = new(big.Int).SetBytes(x).FillBytes(make([]byte, spec.size))
+		y = new(big.Int).SetBytes(y).FillBytes(make([]byte, spec.size))
+	}
+	if len(x) != spec.size || len(y) != spec.size {
+		return nil, fmt.Errorf("%w: coordinates must be %d bytes", ErrMalformedKey, spec.size)
+	}
+
+	// crypto/ecdh rejects points not on the curve, including the point at infinity
+	point := append(append([]byte{4}, x...), y...)
+	var ecdhCurve ecdh.Curve
+	switch Curve(crv) {
+	case CurveP256:
+		ecdhCurve = ecdh.P256()
+	case CurveP384:
+		ecdhCurve = ecdh.P384()
+	case CurveP521:
+		ecdhCurve = ecdh.P521()
+	}
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPoint, err)
+	}
+
+	return &EC2PublicKey{
+		Alg:   alg,
+		Curve: Curve(crv),
+		X:     x,
+		Y:     y,
+		key: &ecdsa.PublicKey{
+			Curve: spec.curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		},
+	}, nil
+}
+
+func (p parser) parseRSA(alg Algorithm, params map[int64]cbor.RawMessage) (*RSAPublicKey, error) {
+	if alg != AlgRS256 && alg != AlgPS256 {
+		return nil, fmt.Errorf("%w: %s with RSA", ErrAlgorithmKeyMismatch, alg)
+	}
+	n, err := p.bytesParam(params, labelCrv)
+	if err != nil {
+		return nil, err
+	}
+	e, err := p.bytesParam(params, labelX)
+	if err != nil {
+		return nil, err
+	}
+	modulus, exponent := new(big.Int).SetBytes(n), new(big.Int).SetBytes(e)
+	if p.lenient {
+		n, e = modulus.Bytes(), exponent.Bytes()
+	}
+	if len(n) == 0 || n[0] == 0 || len(e) == 0 || e[0] == 0 {
+		return nil, fmt.Errorf("%w: modulus and exponent must be minimally encoded", ErrInvalidRSAKey)
+	}
+	if !p.lenient {
+		if bits := modulus.BitLen(); bits < MinRSABits || bits > MaxRSABits {
+			return nil, fmt.Errorf("%w: %d bits", ErrRSAKeySize, bits)
+		}
+		if modulus.Bit(0) == 0 {
+			return nil, fmt.Errorf("%w: even modulus", ErrInvalidRSAKey)
+		}
+	}
+	// crypto/rsa only supports exponents fitting in 31 bits
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 || exponent.Bit(0) == 0 {
+		return nil, fmt.Errorf("%w: exponent out of range", ErrInvalidRSAKey)
+	}
+	return &RSAPublicKey{
+		Alg: alg,
+		N:   n,
+		E:   e,
+		key: &rsa.PublicKey{N: modulus, E: int(exponent.Int64())},
+	}, nil
+}
+
+func (p parser) parseOKP(alg Algorithm, params map[int64]cbor.RawMessage) (*OKPPublicKey, error) {
+	var crv int64
+	if err := p.intParam(params, labelCrv, &crv); err != nil {
+		return nil, err
+	}
+	if Curve(crv) != CurveEd25519 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedCurve, crv)
+	}
+	if alg != AlgEdDSA {
+		return nil, fmt.Errorf("%w: %s on %s", ErrAlgorithmKeyMismatch, alg, Curve(crv))
+	}
+	x, err := p.bytesParam(params, labelX)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: Ed25519 keys must be %d bytes", ErrMalformedKey, ed25519.PublicKeySize)
+	}
+	return &OKPPublicKey{Alg: alg, Curve: CurveEd25519, X: x}, nil
+}
+
+func (k *EC2PublicKey) Algorithm() Algorithm              { return k.Alg }
+func (k *EC2PublicKey) KeyType() KeyType                  { return KeyTypeEC2 }
+func (k *EC2PublicKey) CryptoPublicKey() crypto.PublicKey { return k.key }
+
+// Verify checks an ASN.1 DER ECDSA signature, as WebAuthn authenticators produce.
+func (k *EC2PublicKey) Verify(data, signature []byte) error {
+	if !ecdsa.VerifyASN1(k.key, digest(k.Alg.hash(), data), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (k *RSAPublicKey) Algorithm() Algorithm              { return k.Alg }
+func (k *RSAPublicKey) KeyType() KeyType                  { return KeyTypeRSA }
+func (k *RSAPublicKey) CryptoPublicKey() crypto.PublicKey { return k.key }
+
+// Bits returns the size of the modulus.
+func (k *RSAPublicKey) Bits() int { return k.key.N.BitLen() }
+
+// Verify checks a PKCS #1 v1.5 (RS256) or PSS (PS256) signature.
+func (k *RSAPublicKey) Verify(data, signature []byte) error {
+	hash := k.Alg.hash()
+	var err error
+	if k.Alg == AlgPS256 {
+		err = rsa.VerifyPSS(k.key, hash, digest(hash, data), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	} else {
+		err = rsa.VerifyPKCS1v15(k.key, hash, digest(hash, data), signature)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+func (k *OKPPublicKey) Algorithm() Algorithm              { return k.Alg }
+func (k *OKPPublicKey) KeyType() KeyType                  { return KeyTypeOKP }
+func (k *OKPPublicKey) CryptoPublicKey() crypto.PublicKey { return ed25519.PublicKey(k.X) }
+
+// Verify checks a pure Ed25519 signature.
+func (k *OKPPublicKey) Verify(data, signature []byte) error {
+	if !ed25519.Verify(ed25519.PublicKey(k.X), data, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// hash returns the hash function of the algorithm, 0 for EdDSA.
+func (a Algorithm) hash() crypto.Hash {
+	switch a {
+	case AlgES256, AlgPS256, AlgRS256:
+		return crypto.SHA256
+	case AlgES384:
+		return crypto.SHA384
+	case AlgES512:
+		return crypto.SHA512
+	default:
+		return 0
+	}
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,57 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const credPropsIdentifier = "credProps"
+
+// CredPropsOutput is the output of the credProps extension.
+type CredPropsOutput struct {
+	ResidentKey *bool `json:"rk,omitempty"` // Whether the credential is discoverable, nil if the client doesn't know
+}
+
+// CredPropsExtension asks the client whether the new credential is discoverable.
+// Registration only, BeginRegistration always requests it.
+type CredPropsExtension struct{}
+
+// Identifier returns "credProps".
+func (CredPropsExtension) Identifier() string { return credPropsIdentifier }
+
+// AddInput requests the extension.
+func (CredPropsExtension) AddInput(c *CeremonyOptions) error {
+	if c.Ceremony != CeremonyRegistration {
+		return fmt.Errorf("%w: credProps", ErrOptionNotApplicable)
+	}
+	return c.SetExtensionInput(credPropsIdentifier, true)
+}
+
+// ValidateAuthenticatorOutput does nothing, credProps is a client-only extension.
+func (CredPropsExtension) ValidateAuthenticatorOutput(Ceremony, *AuthenticatorExtensionOutputs, *Extensions) error {
+	return nil
+}
+
+// ValidateClientOutput records the reported credential properties.
+func (CredPropsExtension) ValidateClientOutput(_ Ceremony, output json.RawMessage, result *Extensions) error {
+	if output == nil {
+		return nil
+	}
+	var out CredPropsOutput
+	if err := decodeExtensionOutput(credPropsIdentifier, output, &out); err != nil {
+		return err
+	}
+	result.CredProps = &out
+	return nil
+}
+
+func decodeCredPropsExtension(ceremony Ceremony, inputs ExtensionInputs) (Extension, error) {
+	var requested bool
+	if _, err := decodeExtensionInput(inputs, credPropsIdentifier, &requested); err != nil {
+		return nil, err
+	}
+	if !requested || ceremony != CeremonyRegistration {
+		return nil, nil
+	}
+	return CredPropsExtension{}, nil
+}
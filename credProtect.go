@@ -0,0 +1,149 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CredProtectPolicy is the credentialProtectionPolicy input of the CTAP credProtect extension.
+type CredProtectPolicy string
+
+const (
+	CredProtectUVOptional                     CredProtectPolicy = "userVerificationOptional"
+	CredProtectUVOptionalWithCredentialIDList CredProtectPolicy = "userVerificationOptionalWithCredentialIDList"
+	CredProtectUVRequired                     CredProtectPolicy = "userVerificationRequired"
+)
+
+// IsValid checks if the CredProtectPolicy is one of the defined constants.
+func (p CredProtectPolicy) IsValid() bool {
+	return p.Level() != CredProtectLevelNone
+}
+
+// Level returns the authenticator level matching the policy.
+func (p CredProtectPolicy) Level() CredProtectLevel {
+	switch p {
+	case CredProtectUVOptional:
+		return CredProtectLevelUVOptional
+	case CredProtectUVOptionalWithCredentialIDList:
+		return CredProtectLevelUVOptionalWithCredentialIDList
+	case CredProtectUVRequired:
+		return CredProtectLevelUVRequired
+	default:
+		return CredProtectLevelNone
+	}
+}
+
+// CredProtectLevel is the credProtect value the authenticator reports in the authenticator data.
+// Higher levels protect the credential better.
+type CredProtectLevel uint8
+
+const (
+	CredProtectLevelNone                           CredProtectLevel = 0 // Not reported
+	CredProtectLevelUVOptional                     CredProtectLevel = 1 // Usable without UV
+	CredProtectLevelUVOptionalWithCredentialIDList CredProtectLevel = 2 // Discoverable only with UV, usable without it if the ID is known
+	CredProtectLevelUVRequired                     CredProtectLevel = 3 // Neither discoverable nor usable without UV
+)
+
+// IsValid checks if the level is one defined by CTAP (0 means not reported and is not valid).
+func (l CredProtectLevel) IsValid() bool {
+	return l >= CredProtectLevelUVOptional && l <= CredProtectLevelUVRequired
+}
+
+// String returns the policy name of the level.
+func (l CredProtectLevel) String() string {
+	switch l {
+	case CredProtectLevelUVOptional:
+		return string(CredProtectUVOptional)
+	case CredProtectLevelUVOptionalWithCredentialIDList:
+		return string(CredProtectUVOptionalWithCredentialIDList)
+	case CredProtectLevelUVRequired:
+		return string(CredProtectUVRequired)
+	default:
+		return "none"
+	}
+}
+
+const (
+	credProtectIdentifier = "credProtect"
+	// Client inputs of the credProtect extension
+	credProtectPolicyInput  = "credentialProtectionPolicy"
+	credProtectEnforceInput = "enforceCredentialProtectionPolicy"
+)
+
+// CredProtectExtension requests the CTAP credProtect extension, see WithCredProtect.
+type CredProtectExtension struct {
+	Policy  CredProtectPolicy
+	Enforce bool
+}
+
+// WithCredProtect requests the credProtect extension, e.g., to require UV before a roaming security key
+// even reveals the credential. If enforce is set, the browser fails if the authenticator can't apply the policy,
+// and FinishRegistration rejects a weaker reported level (RegistrationData.Options must be set). Registration only.
+func WithCredProtect(policy CredProtectPolicy, enforce bool) Option {
+	return WithExtension(&CredProtectExtension{Policy: policy, Enforce: enforce})
+}
+
+// Identifier returns "credProtect".
+func (e *CredProtectExtension) Identifier() string { return credProtectIdentifier }
+
+// AddInput adds the policy and, if enforced, the enforcement flag.
+func (e *CredProtectExtension) AddInput(c *CeremonyOptions) error {
+	if c.Ceremony != CeremonyRegistration {
+		return fmt.Errorf("%w: credProtect", ErrOptionNotApplicable)
+	}
+	if !e.Policy.IsValid() {
+		return fmt.Errorf("%w: %q", ErrInvalidCredProtectPolicy, e.Policy)
+	}
+	if err := c.SetExtensionInput(credProtectPolicyInput, e.Policy); err != nil {
+		return err
+	}
+	if e.Enforce {
+		return c.SetExtensionInput(credProtectEnforceInput, true)
+	}
+	return nil
+}
+
+// ValidateAuthenticatorOutput records the level applied by the authenticator and enforces the policy if requested.
+func (e *CredProtectExtension) ValidateAuthenticatorOutput(_ Ceremony, outputs *AuthenticatorExtensionOutputs, result *Extensions) error {
+	if output, ok := outputs.Take(credProtectIdentifier); ok {
+		level, err := parseCredProtectLevel(output)
+		if err != nil {
+			return err
+		}
+		result.CredProtect = level
+	}
+	// Authenticators not reporting credProtect apply the CTAP default, userVerificationOptional
+	applied := result.CredProtect
+	if applied == CredProtectLevelNone {
+		applied = CredProtectLevelUVOptional
+	}
+	if e.Enforce && applied < e.Policy.Level() {
+		return fmt.Errorf("%w: requested %s, got %s", ErrCredProtectNotSatisfied, e.Policy.Level(), applied)
+	}
+	return nil
+}
+
+// ValidateClientOutput does nothing, credProtect has no client output.
+func (e *CredProtectExtension) ValidateClientOutput(Ceremony, json.RawMessage, *Extensions) error {
+	return nil
+}
+
+func decodeCredProtectExtension(ceremony Ceremony, inputs ExtensionInputs) (Extension, error) {
+	ext := &CredProtectExtension{}
+	if ok, err := decodeExtensionInput(inputs, credProtectPolicyInput, &ext.Policy); !ok || ceremony != CeremonyRegistration {
+		return nil, err
+	}
+	if _, err := decodeExtensionInput(inputs, credProtectEnforceInput, &ext.Enforce); err != nil {
+		return nil, err
+	}
+	return ext, nil
+}
+
+// parseCredProtectLevel decodes the credProtect authenticator extension output.
+func parseCredProtectLevel(output interface{}) (CredProtectLevel, error) {
+	v, ok := output.(uint64)
+	if !ok || !CredProtectLevel(v).IsValid() {
+		return CredProtectLevelNone, fmt.Errorf("%w: %v", ErrInvalidCredProtectOutput, output)
+	}
+	return CredProtectLevel(v), nil
+}
@@ -0,0 +1,89 @@
+package webauthn
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"slices"
+)
+
+// minFakeCredentialSecretLength is the minimum length of Config.FakeCredentialSecret (bytes).
+const minFakeCredentialSecretLength = 32
+
+// fakeCredentialTransports mimic the transports reported by common authenticators, sorted like browsers report them.
+var fakeCredentialTransports = [...][]AuthenticatorTransport{
+	{TransportInternal},
+	{TransportHybrid, TransportInternal},
+	{TransportUSB},
+	{TransportNFC, TransportUSB},
+}
+
+// fakeCredentialIDLengths mimic the credential ID lengths of common authenticators
+// (platform passkeys use 16-32 bytes, security keys often 64).
+var fakeCredentialIDLengths = [...]int{16, 20, 32, 64}
+
+// BeginLoginForUser works like BeginLogin, but doesn't reveal whether the username exists.
+// Pass the user's credential IDs, or none for unknown and credential-less users. In the latter case
+// the allow list is filled with fake credential IDs derived from Config.FakeCredentialSecret and the
+// username, so repeated requests for the same username always look the same, like for a real user.
+// The fake IDs are computed on both paths to keep the timing comparable. With WithCredentialTransports,
+// the fake credentials get deterministic transports too.
+func (w *WebAuthn) BeginLoginForUser(username string, allowedCredentialIDs []string, opts ...Option) (*PublicKeyCredentialRequestOptions, error) {
+	if w == nil {
+		return nil, ErrNilInstance
+	}
+	if len(w.Config.FakeCredentialSecret) == 0 {
+		return nil, ErrMissingFakeCredentialSecret
+	}
+
+	fakeIDs := w.fakeCredentialIDs(username)
+	if len(allowedCredentialIDs) == 0 {
+		allowedCredentialIDs = fakeIDs
+		opts = append(opts, w.fakeTransports(username))
+	}
+	return w.BeginLogin(allowedCredentialIDs, opts...)
+}
+
+// fakeTransports adds deterministic transports to the fake credentials if real ones would have them,
+// otherwise the missing hints would tell fake and real users apart.
+func (w *WebAuthn) fakeTransports(username string) Option {
+	return func(c *CeremonyOptions) error {
+		if !c.transportHints {
+			return nil
+		}
+		seed := w.fakeCredentialMAC([]byte("transports"), username)
+		for i := range c.Login.AllowCredentials {
+			c.Login.AllowCredentials[i].Transports = slices.Clone(fakeCredentialTransports[int(seed[i])%len(fakeCredentialTransports)])
+		}
+		return nil
+	}
+}
+
+// fakeCredentialIDs derives one or two deterministic, base64url encoded credential IDs for the username.
+func (w *WebAuthn) fakeCredentialIDs(username string) []string {
+	seed := w.fakeCredentialMAC([]byte("seed"), username)
+	count := 1 + int(seed[0]%2)
+
+	ids := make([]string, count)
+	for i := range ids {
+		length := fakeCredentialIDLengths[int(seed[1+i])%len(fakeCredentialIDLengths)]
+		id := make([]byte, 0, length+sha256.Size)
+		// Expand with a counter, as a single HMAC output is shorter than the longest IDs
+		for block := uint32(0); len(id) < length; block++ {
+			info := binary.BigEndian.AppendUint32([]byte("credential"), uint32(i)<<16|block)
+			id = append(id, w.fakeCredentialMAC(info, username)...)
+		}
+		ids[i] = base64.RawURLEncoding.EncodeToString(id[:length])
+	}
+	return ids
+}
+
+// fakeCredentialMAC computes HMAC-SHA256(secret, info || 0x00 || username).
+func (w *WebAuthn) fakeCredentialMAC(info []byte, username string) []byte {
+	mac := hmac.New(sha256.New, w.Config.FakeCredentialSecret)
+	mac.Write(info)
+	mac.Write([]byte{0})
+	mac.Write([]byte(username))
+	return mac.Sum(nil)
+}
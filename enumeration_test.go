@@ -0,0 +1,187 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func newEnumerationWebAuthn(t *testing.T, secret []byte) *WebAuthn {
+	t.Helper()
+	w, err := New(&Config{
+		RPID:                 "example.com",
+		RPDisplayName:        "Example",
+		RPOrigins:            []string{"https://example.com"},
+		Timeout:              60000,
+		UserVerification:     UVPreferred,
+		Attestation:          AttestationNone,
+		FakeCredentialSecret: secret,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return w
+}
+
+// realTransports are the hints of the real credentials, passed for every user like a handler would.
+var realTransports = WithCredentialTransports(map[string][]AuthenticatorTransport{"BAUG": {TransportInternal}})
+
+func beginLoginForUser(t *testing.T, w *WebAuthn, username string, credentialIDs []string) *PublicKeyCredentialRequestOptions {
+	t.Helper()
+	options, err := w.BeginLoginForUser(username, credentialIDs, realTransports)
+	if err != nil {
+		t.Fatalf("BeginLoginForUser: %v", err)
+	}
+	return options
+}
+
+// The first fake credential ID is the first bytes of HMAC(secret, "credential" || uint32(0) || 0x00 || username),
+// its length picked by HMAC(secret, "seed" || 0x00 || username).
+func TestFakeCredentialIDsDerivation(t *testing.T) {
+	secret := bytes.Repeat([]byte{1}, 32)
+	w := newEnumerationWebAuthn(t, secret)
+	options := beginLoginForUser(t, w, "alice", nil)
+
+	hmacOf := func(info []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(info)
+		mac.Write([]byte{0})
+		mac.Write([]byte("alice"))
+		return mac.Sum(nil)
+	}
+	seed := hmacOf([]byte("seed"))
+	if want := 1 + int(seed[0]%2); len(options.AllowCredentials) != want {
+		t.Fatalf("BeginLoginForUser() got %d credentials, want %d", len(options.AllowCredentials), want)
+	}
+	length := fakeCredentialIDLengths[int(seed[1])%len(fakeCredentialIDLengths)]
+	var id []byte
+	for block := uint32(0); len(id) < length; block++ {
+		id = append(id, hmacOf(binary.BigEndian.AppendUint32([]byte("credential"), block))...)
+	}
+	if want := base64.RawURLEncoding.EncodeToString(id[:length]); options.AllowCredentials[0].ID != want {
+		t.Errorf("BeginLoginForUser() credential ID = %s, want %s", options.AllowCredentials[0].ID, want)
+	}
+	transports := fakeCredentialTransports[int(hmacOf([]byte("transports"))[0])%len(fakeCredentialTransports)]
+	if !slices.Equal(options.AllowCredentials[0].Transports, transports) {
+		t.Errorf("BeginLoginForUser() transports = %v, want %v", options.AllowCredentials[0].Transports, transports)
+	}
+}
+
+func TestFakeCredentialsStable(t *testing.T) {
+	w := newEnumerationWebAuthn(t, bytes.Repeat([]byte{1}, 32))
+	first := beginLoginForUser(t, w, "alice", nil)
+	second := beginLoginForUser(t, w, "alice", nil)
+	if !slices.EqualFunc(first.AllowCredentials, second.AllowCredentials, equalDescriptors) {
+		t.Errorf("BeginLoginForUser() = %+v, then %+v", first.AllowCredentials, second.AllowCredentials)
+	}
+	if first.Challenge == second.Challenge {
+		t.Errorf("BeginLoginForUser() reused challenge %s", first.Challenge)
+	}
+
+	// Other usernames and other secrets give other credentials
+	for _, username := range []string{"bob", "Alice", "alice "} {
+		other := beginLoginForUser(t, w, username, nil)
+		if sharesCredentialID(first.AllowCredentials, other.AllowCredentials) {
+			t.Errorf("BeginLoginForUser(%q) = %+v, same as for alice", username, other.AllowCredentials)
+		}
+	}
+	other := beginLoginForUser(t, newEnumerationWebAuthn(t, bytes.Repeat([]byte{2}, 32)), "alice", nil)
+	if sharesCredentialID(first.AllowCredentials, other.AllowCredentials) {
+		t.Errorf("BeginLoginForUser() with another secret = %+v, same as with the first", other.AllowCredentials)
+	}
+
+	// Without transport hints for real credentials, fake ones get none either
+	options, err := w.BeginLoginForUser("alice", nil)
+	if err != nil {
+		t.Fatalf("BeginLoginForUser: %v", err)
+	}
+	for _, c := range options.AllowCredentials {
+		if len(c.Transports) != 0 {
+			t.Errorf("BeginLoginForUser() without hints got transports %v", c.Transports)
+		}
+	}
+}
+
+// A fake user's options must have the same JSON shape as a real user's, and plausible credential IDs.
+func TestFakeCredentialsShape(t *testing.T) {
+	w := newEnumerationWebAuthn(t, bytes.Repeat([]byte{1}, 32))
+	realUser := beginLoginForUser(t, w, "alice", []string{"BAUG"})
+	if realUser.AllowCredentials[0].ID != "BAUG" {
+		t.Fatalf("BeginLoginForUser() for a real user = %+v", realUser.AllowCredentials)
+	}
+	realShape := jsonShape(t, realUser)
+
+	for _, username := range []string{"bob", "carol", "dave", "erin", "frank"} {
+		fake := beginLoginForUser(t, w, username, nil)
+		if shape := jsonShape(t, fake); !maps.Equal(shape, realShape) {
+			t.Errorf("BeginLoginForUser(%q) shape = %v, want %v", username, shape, realShape)
+		}
+		if fake.RPID != realUser.RPID || fake.Timeout != realUser.Timeout || fake.UserVerification != realUser.UserVerification {
+			t.Errorf("BeginLoginForUser(%q) = %+v, want the settings of %+v", username, fake, realUser)
+		}
+		for _, c := range fake.AllowCredentials {
+			id, err := base64.RawURLEncoding.DecodeString(c.ID)
+			if err != nil || !slices.Contains(fakeCredentialIDLengths[:], len(id)) {
+				t.Errorf("BeginLoginForUser(%q) credential ID %s has %d bytes, err = %v", username, c.ID, len(id), err)
+			}
+			if c.Type != realUser.AllowCredentials[0].Type || len(c.Transports) == 0 {
+				t.Errorf("BeginLoginForUser(%q) credential = %+v, want type %s with transports", username, c, realUser.AllowCredentials[0].Type)
+			}
+		}
+	}
+}
+
+func equalDescriptors(a, b PublicKeyCredentialDescriptor) bool {
+	return a.Type == b.Type && a.ID == b.ID && slices.Equal(a.Transports, b.Transports)
+}
+
+func sharesCredentialID(a, b []PublicKeyCredentialDescriptor) bool {
+	for _, c := range a {
+		if slices.ContainsFunc(b, func(d PublicKeyCredentialDescriptor) bool { return d.ID == c.ID }) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonShape returns the JSON keys of the options and of their first allowed credential, with their value types.
+func jsonShape(t *testing.T, options *PublicKeyCredentialRequestOptions) map[string]string {
+	t.Helper()
+	raw, err := json.Marshal(options)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	shape := make(map[string]string)
+	for k, v := range decoded {
+		shape[k] = typeName(v)
+	}
+	for k, v := range decoded["allowCredentials"].([]any)[0].(map[string]any) {
+		shape["allowCredentials[]."+k] = typeName(v)
+	}
+	return shape
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return "other"
+	}
+}
@@ -10,6 +10,10 @@ var (
 	ErrInvalidRPOrigins                            = errors.New("invalid RP origins")
 	ErrInvalidRPOrigin                             = errors.New("invalid RP origin")
 	ErrEmptyRPID                                   = errors.New("RP ID cannot be empty")
+	ErrRPIDIsPublicSuffix                          = errors.New("RP ID cannot be a public suffix")
+	ErrOriginRPIDMismatch                          = errors.New("origin is not within the RP ID")
+	ErrInvalidRelatedOrigin                        = errors.New("invalid related origin")
+	ErrTooManyRelatedOriginLabels                  = errors.New("too many registrable domain labels in related origins")
 	ErrEmptyRPDisplayName                          = errors.New("RP display name cannot be empty")
 	ErrInvalidTimeout                              = errors.New("timeout must be greater than 0")
 	ErrParsingOrigin                               = errors.New("error parsing origin")
@@ -42,6 +46,62 @@ var (
 	ErrATFlagButNoData                             = errors.New("AT flag set, but no data remains for public key")
 	ErrEDFlagButNoData                             = errors.New("ED flag set, but no data remains after parsing previous parts")
 	ErrFailedDecodeExtensionData                   = errors.New("failed to decode extension data")
+	ErrNoTenants                                   = errors.New("at least one tenant configuration is required")
+	ErrEmptyTenantKey                              = errors.New("tenant key cannot be empty")
+	ErrUnknownTenant                               = errors.New("unknown tenant")
+	ErrNoTenantForOrigin                           = errors.New("no tenant allows origin")
+	ErrDuplicateTenantOrigin                       = errors.New("origin is configured for more than one tenant")
+	ErrEmptyRPSelector                             = errors.New("RP selector needs a tenant key or an origin")
+	ErrNilLoginData                                = errors.New("login data cannot be nil")
+	ErrSavingChallenge                             = errors.New("error saving challenge")
+	ErrConsumingChallenge                          = errors.New("error consuming challenge")
+	ErrChallengeNotFound                           = errors.New("challenge not found or expired")
+	ErrNilCredentialResolver                       = errors.New("credential resolver cannot be nil")
+	ErrCredentialNotFound                          = errors.New("credential not found")
+	ErrFailedDecodeUserHandle                      = errors.New("failed to decode user handle")
+	ErrUserHandleMismatch                          = errors.New("user handle does not match credential owner")
+	ErrMissingFakeCredentialSecret                 = errors.New("fake credential secret is not configured")
+	ErrFakeCredentialSecretTooShort                = errors.New("fake credential secret must be at least 32 bytes")
+	ErrOptionNotApplicable                         = errors.New("option is not applicable to this ceremony")
+	ErrInvalidPRFInputs                            = errors.New("invalid PRF extension inputs")
+	ErrInvalidPRFOutputs                           = errors.New("invalid PRF extension outputs")
+	ErrInvalidLargeBlobInputs                      = errors.New("invalid largeBlob extension inputs")
+	ErrInvalidCredProtectPolicy                    = errors.New("invalid credProtect policy")
+	ErrInvalidCredProtectOutput                    = errors.New("invalid credProtect extension output")
+	ErrCredProtectNotSatisfied                     = errors.New("credProtect level is weaker than requested")
+	ErrChallengeMismatch                           = errors.New("challenge does not match the ceremony options")
+	ErrInvalidAppID                                = errors.New("invalid AppID")
+	ErrInvalidLargeBlobOutputs                     = errors.New("invalid largeBlob extension outputs")
+	ErrFailedEncodeExtensionInput                  = errors.New("failed to encode extension input")
+	ErrInvalidExtensionInput                       = errors.New("invalid extension input")
+	ErrInvalidExtensionOutput                      = errors.New("invalid extension output")
+	ErrUnrequestedExtension                        = errors.New("extension output was not requested")
+	ErrTypeNotPaymentGet                           = errors.New("client data type is not payment.get")
+	ErrInvalidPaymentDetails                       = errors.New("invalid payment details")
+	ErrMissingPaymentOptions                       = errors.New("payment confirmation options are required")
+	ErrPaymentMismatch                             = errors.New("confirmed payment does not match the request")
+	ErrMissingTransactionProofKey                  = errors.New("transaction proof key is not configured")
+	ErrTransactionProofKeyTooShort                 = errors.New("transaction proof key must be at least 32 bytes")
+	ErrEmptyTransactionDigest                      = errors.New("transaction digest cannot be empty")
+	ErrMissingTransactionChallenge                 = errors.New("transaction challenge is required")
+	ErrInvalidTransactionProof                     = errors.New("invalid transaction proof")
+	ErrInvalidResidentKey                          = errors.New("invalid resident key requirement")
+	ErrInvalidAuthenticatorAttachment              = errors.New("invalid authenticator attachment")
+	ErrInvalidCredentialType                       = errors.New("credential type is not public-key")
+	ErrRawIDMismatch                               = errors.New("rawId does not match id")
+	ErrAlgorithmMismatch                           = errors.New("public key algorithm doesn't match the credential public key")
+	ErrPublicKeyMismatch                           = errors.New("reported public key doesn't match the credential public key")
+	ErrRateLimited                                 = errors.New("too many failed attempts")
+	ErrLimiterFailed                               = errors.New("limiter failed")
+	ErrAlgorithmNotOffered                         = errors.New("credential algorithm was not offered in pubKeyCredParams")
+	ErrUnsupportedAlgorithm                        = errors.New("unsupported COSE algorithm")
+	ErrInvalidComplianceProfile                    = errors.New("invalid compliance profile")
+	ErrAlgorithmNotAllowed                         = errors.New("algorithm not allowed by the compliance profile")
+	ErrRSAKeyTooSmall                              = errors.New("RSA key too small for the compliance profile")
+	ErrInvalidAttestationCertificate               = errors.New("invalid attestation certificate")
+	ErrWeakAttestationCertificate                  = errors.New("attestation certificate signature algorithm not allowed by the compliance profile")
+	ErrWeakAttestationAlgorithm                    = errors.New("attestation signature algorithm not allowed by the compliance profile")
+	ErrNilChallengeStore                           = errors.New("conditional challenge store is not configured")
 	ErrFailedUnmarshalPublicKeyCredential          = errors.New("failed to unmarshal public key credential")
 	ErrFailedUnmarshalPublicKeyCredentialAssertion = errors.New("failed to unmarshal public key credential assertion")
 )
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"crypto/rand"
 	"database/sql"
 	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/session"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -14,8 +17,9 @@ import (
 )
 
 var (
-	w  *webauthn.WebAuthn
-	db *sql.DB
+	w        *webauthn.WebAuthn
+	db       *sql.DB
+	sessions *session.Manager
 )
 
 // UserSessionData holds the user entity and their credential details for this example.
@@ -36,22 +40,44 @@ func sendJSONError(c *fiber.Ctx, statusCode int, message string, err error) erro
 
 func main() {
 	var err error
+	// Secret for fake credentials of unknown users. It's random on every start here,
+	// but in production it should be loaded from your secret storage, so it stays stable.
+	fakeCredentialSecret := make([]byte, 32)
+	if _, err = rand.Read(fakeCredentialSecret); err != nil {
+		log.Fatalf("Failed to generate fake credential secret: %v", err)
+	}
+
 	// Initialize WebAuthn library
 	// Relying Party configuration MUST match the client-side
 	// RPOrigin, and RPID should be based on your actual domain.
 	w, err = webauthn.New(&webauthn.Config{
-		RPID:             "localhost",                       // Domain name only - must match the domain in your URL
-		RPDisplayName:    "WebAuthn2Go Example",             // Display name
-		RPOrigins:        []string{"http://localhost:8080"}, // Allowed origins - with protocol and port
-		Timeout:          300_000,                           // Milliseconds, 5 minutes, recommended default value if userVerification is preferred or required, 2 mins if discouraged
-		UserVerification: webauthn.UVPreferred,              // User verification requirement
-		Attestation:      webauthn.AttestationIndirect,      // Attestation preference, Indirect gives us AAGUID
-		Debug:            true,                              // Enable debug logging
+		RPID:                 "localhost",                       // Domain name only - must match the domain in your URL
+		RPDisplayName:        "WebAuthn2Go Example",             // Display name
+		RPOrigins:            []string{"http://localhost:8080"}, // Allowed origins - with protocol and port
+		Timeout:              300_000,                           // Milliseconds, 5 minutes, recommended default value if userVerification is preferred or required, 2 mins if discouraged
+		UserVerification:     webauthn.UVPreferred,              // User verification requirement
+		Attestation:          webauthn.AttestationIndirect,      // Attestation preference, Indirect gives us AAGUID
+		Debug:                true,                              // Enable debug logging
+		FakeCredentialSecret: fakeCredentialSecret,              // Used by BeginLoginForUser
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize WebAuthn: %v", err)
 	}
 
+	// Session tokens issued after login. As above, load the key from your secret storage in production,
+	// and add new keys in front of the old ones to rotate them.
+	sessionKey := make([]byte, 32)
+	if _, err = rand.Read(sessionKey); err != nil {
+		log.Fatalf("Failed to generate session key: %v", err)
+	}
+	sessions, err = session.New(session.Config{
+		Keys:           []session.Key{{ID: "example-1", Secret: sessionKey}},
+		InsecureCookie: true, // The example is served over plain HTTP
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize sessions: %v", err)
+	}
+
 	// Init SQLite database
 	db, err = sql.Open("sqlite3", "./webauthn.db")
 	if err != nil {
@@ -173,10 +199,7 @@ func finishRegistration(c *fiber.Ctx) error {
 	log.Printf("Finish Registration - User: %s, Email: %s, Credential ID: %s", sessionData.User.DisplayName, email, payload.ID)
 
 	// 4. Prepare data for the library
-	registrationData := webauthn.RegistrationData{
-		ClientDataJSON:    payload.ClientDataJSON,
-		AttestationObject: payload.AttestationObject,
-	}
+	registrationData := payload.RegistrationData(nil)
 
 	// 5. Call library
 	result, err := w.FinishRegistration(registrationData)
@@ -195,6 +218,9 @@ func finishRegistration(c *fiber.Ctx) error {
 	}
 
 	log.Printf("Registration successful for %s (%s)! Stored CredID: %s, AAGUID: %s, Name: %s", sessionData.User.DisplayName, email, payload.ID, result.AAGUID, result.AuthenticatorName)
+	if result.Discoverable != nil {
+		log.Printf("Credential is discoverable (passkey): %t", *result.Discoverable)
+	}
 
 	// 7. Clear active challenge
 	err = deleteChallenge(payload.ClientData().Challenge)
@@ -225,25 +251,22 @@ func beginLogin(c *fiber.Ctx) error {
 		return sendJSONError(c, fiber.StatusBadRequest, "Email is required", nil)
 	}
 
-	// 2. Retrieve user session data
+	// 2. Retrieve user session data and the registered credential, if any
+	var allowedCredentialIDs []string
 	sessionData, err := getUser(reqBody.Email)
-	if err != nil {
-		log.Printf("Login attempt for unregistered email: %s", reqBody.Email)
-		return sendJSONError(c, fiber.StatusNotFound, "User not found.", nil)
-	}
-
-	// 3. Check if user has a credential registered, you should handle that differently in production
-	if sessionData.CredID == "" || sessionData.PublicKey == nil {
-		log.Printf("Login attempt for user %s with no registered credential.", sessionData.User.DisplayName)
-		return sendJSONError(c, fiber.StatusBadRequest, "No credential registered for this user.", nil)
+	if err == nil && sessionData.CredID != "" && sessionData.PublicKey != nil {
+		allowedCredentialIDs = []string{sessionData.CredID}
+		log.Printf("Begin Login - User: %s, Email: %s", sessionData.User.DisplayName, reqBody.Email)
+	} else {
+		// 3. Unknown users and users without a credential get the same kind of response as real ones,
+		// so this endpoint can't be used to find out who is registered
+		log.Printf("Login attempt for unregistered email or user without credential: %s", reqBody.Email)
 	}
 
-	log.Printf("Begin Login - User: %s, Email: %s", sessionData.User.DisplayName, reqBody.Email)
-
-	// 4. Call library's BeginLogin
-	opts, err := w.BeginLogin([]string{sessionData.CredID})
+	// 4. Call library's BeginLoginForUser, which fills in fake credentials if none are passed
+	opts, err := w.BeginLoginForUser(reqBody.Email, allowedCredentialIDs)
 	if err != nil {
-		log.Printf("BeginLogin failed for user %s: %v", sessionData.User.DisplayName, err)
+		log.Printf("BeginLogin failed for %s: %v", reqBody.Email, err)
 		return sendJSONError(c, fiber.StatusInternalServerError, "Failed to begin login", err)
 	}
 
@@ -326,7 +349,23 @@ func finishLogin(c *fiber.Ctx) error {
 	}
 	log.Printf("Removed active login challenge for %s", email)
 
-	// 9. Respond to client
+	// 9. Issue the session token
+	result.UserID = sessionData.User.ID
+	result.CredentialID = sessionData.CredID
+	token, claims, err := sessions.Issue(result)
+	if err != nil {
+		return sendJSONError(c, fiber.StatusInternalServerError, "Failed to issue session", err)
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     sessions.CookieName(),
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Unix(claims.ExpiresAt, 0),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	// 10. Respond to client
 	return c.JSON(fiber.Map{
 		"success":      true,
 		"userVerified": result.UserVerified,
@@ -89,7 +89,8 @@ async function register() {
             const response = {
                 id: credential.id,
                 attestationObject: bufferToBase64url(credential.response.attestationObject),
-                clientDataJSON: bufferToBase64url(credential.response.clientDataJSON)
+                clientDataJSON: bufferToBase64url(credential.response.clientDataJSON),
+                clientExtensionResults: credential.getClientExtensionResults()
             };
 
 
@@ -0,0 +1,162 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Extension handles one WebAuthn extension in both ceremonies.
+//
+// Requesting an extension (see WithExtension) adds its inputs to the ceremony options. When the ceremony finishes,
+// the requested extensions are rebuilt from the inputs in RegistrationData.Options or LoginData.Options by their
+// ExtensionDecoder, and validate their outputs. Outputs of extensions that were not requested are rejected.
+type Extension interface {
+	// Identifier returns the extension identifier, e.g., "prf". Client outputs are looked up by it.
+	Identifier() string
+	// AddInput adds the extension's client inputs to the options being generated.
+	AddInput(c *CeremonyOptions) error
+	// ValidateAuthenticatorOutput takes the authenticator extension outputs the extension owns,
+	// validates them and records them in result.
+	ValidateAuthenticatorOutput(ceremony Ceremony, outputs *AuthenticatorExtensionOutputs, result *Extensions) error
+	// ValidateClientOutput validates the client extension output and records it in result.
+	// output is nil if the client returned none.
+	ValidateClientOutput(ceremony Ceremony, output json.RawMessage, result *Extensions) error
+}
+
+// ExtensionDecoder rebuilds an extension from the inputs of the ceremony options.
+// It returns nil if the inputs don't request the extension.
+type ExtensionDecoder func(ceremony Ceremony, inputs ExtensionInputs) (Extension, error)
+
+// builtinExtensionDecoders rebuild the extensions supported out of the box.
+var builtinExtensionDecoders = []ExtensionDecoder{
+	decodeCredPropsExtension,
+	decodePRFExtension,
+	decodeLargeBlobExtension,
+	decodeCredProtectExtension,
+	decodeAppIDExtension,
+	decodeAppIDExcludeExtension,
+	decodePaymentExtension,
+}
+
+// ExtensionInputs holds the client extension inputs of a ceremony, keyed by input name.
+type ExtensionInputs map[string]json.RawMessage
+
+// ClientExtensionResults holds the outputs of PublicKeyCredential.getClientExtensionResults(), keyed by extension identifier.
+type ClientExtensionResults map[string]json.RawMessage
+
+// AuthenticatorExtensionOutputs holds the extension outputs from the authenticator data.
+// Extensions take the outputs they own, the remaining ones were not requested.
+type AuthenticatorExtensionOutputs struct {
+	outputs map[string]interface{}
+	taken   map[string]bool
+}
+
+// Take returns the output with the identifier and marks it as handled.
+func (o *AuthenticatorExtensionOutputs) Take(identifier string) (output interface{}, ok bool) {
+	output, ok = o.outputs[identifier]
+	if ok {
+		o.taken[identifier] = true
+	}
+	return output, ok
+}
+
+// Extensions holds the validated extension outputs of a ceremony.
+type Extensions struct {
+	CredProps    *CredPropsOutput  `json:"credProps,omitempty"`
+	PRF          *PRFOutputs       `json:"prf,omitempty"`
+	LargeBlob    *LargeBlobOutputs `json:"largeBlob,omitempty"`
+	CredProtect  CredProtectLevel  `json:"credProtect,omitempty"`  // Level applied by the authenticator
+	AppID        bool              `json:"appid,omitempty"`        // Assertion made by a legacy U2F credential
+	AppIDExclude bool              `json:"appidExclude,omitempty"` // Exclude list checked against the AppID too
+	Custom       map[string]any    `json:"custom,omitempty"`       // Outputs of custom extensions, keyed by identifier
+}
+
+// SetCustom records the output of a custom extension.
+func (e *Extensions) SetCustom(identifier string, output any) {
+	if e.Custom == nil {
+		e.Custom = make(map[string]any)
+	}
+	e.Custom[identifier] = output
+}
+
+// decodeExtensions rebuilds the extensions requested by the inputs, built-in ones first.
+func (w *WebAuthn) decodeExtensions(ceremony Ceremony, inputs ExtensionInputs) ([]Extension, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	decoders := append(append([]ExtensionDecoder{}, builtinExtensionDecoders...), w.Config.Extensions...)
+	exts := make([]Extension, 0, len(inputs))
+	for _, decode := range decoders {
+		ext, err := decode(ceremony, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if ext != nil {
+			exts = append(exts, ext)
+		}
+	}
+	return exts, nil
+}
+
+// validateExtensions validates the outputs of the requested extensions. If the ceremony options are known,
+// any other outputs are rejected; without them, what was requested is unknown, so other outputs are ignored.
+func validateExtensions(ceremony Ceremony, exts []Extension, clientOutputs ClientExtensionResults, authenticatorOutputs map[string]interface{}, knownOptions bool) (result Extensions, err error) {
+	authOutputs := &AuthenticatorExtensionOutputs{outputs: authenticatorOutputs, taken: make(map[string]bool)}
+	requested := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		requested[ext.Identifier()] = true
+		if err := ext.ValidateAuthenticatorOutput(ceremony, authOutputs, &result); err != nil {
+			return result, err
+		}
+		if err := ext.ValidateClientOutput(ceremony, clientOutputs[ext.Identifier()], &result); err != nil {
+			return result, err
+		}
+	}
+
+	if !knownOptions {
+		return result, nil
+	}
+	if id := firstUnhandled(clientOutputs, requested); id != "" {
+		return result, fmt.Errorf("%w: client output %s", ErrUnrequestedExtension, id)
+	}
+	if id := firstUnhandled(authenticatorOutputs, authOutputs.taken); id != "" {
+		return result, fmt.Errorf("%w: authenticator output %s", ErrUnrequestedExtension, id)
+	}
+	return result, nil
+}
+
+// firstUnhandled returns the alphabetically first key of outputs missing from handled, for stable errors.
+func firstUnhandled[V any](outputs map[string]V, handled map[string]bool) string {
+	var unhandled []string
+	for id := range outputs {
+		if !handled[id] {
+			unhandled = append(unhandled, id)
+		}
+	}
+	if len(unhandled) == 0 {
+		return ""
+	}
+	sort.Strings(unhandled)
+	return unhandled[0]
+}
+
+// decodeExtensionInput unmarshals the input with the name into v, reporting whether it was present.
+func decodeExtensionInput(inputs ExtensionInputs, name string, v any) (bool, error) {
+	raw, ok := inputs[name]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("%w %s: %w", ErrInvalidExtensionInput, name, err)
+	}
+	return true, nil
+}
+
+// decodeExtensionOutput unmarshals the client output of the extension into v.
+func decodeExtensionOutput(identifier string, output json.RawMessage, v any) error {
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("%w %s: %w", ErrInvalidExtensionOutput, identifier, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package fiberwebauthn mounts the WebAuthn ceremonies as Fiber routes and guards routes with post-login sessions.
+// It reuses the stores and JSON API of the httpapi package, and lives in its own module,
+// so the core library doesn't depend on Fiber.
+package fiberwebauthn
+
+import (
+	"errors"
+	"net/http"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/httpapi"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+var ErrNilSessions = errors.New("session manager cannot be nil")
+
+// fiberCtxKey stores the *fiber.Ctx in the request context, so hooks called by httpapi can get it back.
+type fiberCtxKey struct{}
+
+// Config configures the routes mounted by Mount.
+type Config struct {
+	WebAuthn     *webauthn.WebAuthn
+	Users        httpapi.UserStore
+	Credentials  httpapi.CredentialStore
+	Challenges   httpapi.ChallengeStore // Defaults to an httpapi.MemoryStore
+	MaxBodyBytes int64                  // 64 KiB by default
+	// Sessions issues a session after every successful login, see RequireWebAuthnSession.
+	Sessions SessionManager
+	// OnUserCreated is called after a registration created the user, e.g., to set up their account. Optional.
+	OnUserCreated func(c *fiber.Ctx, user webauthn.UserEntity, result *webauthn.RegistrationResult) error
+	// OnCredentialAdded is called after a user with a session registered an additional credential. Optional.
+	OnCredentialAdded func(c *fiber.Ctx, user webauthn.UserEntity, result *webauthn.RegistrationResult) error
+	// OnError is called with every error sent to a client, e.g., for logging. Optional.
+	OnError func(c *fiber.Ctx, err *httpapi.Error)
+	// ClientIP returns the client address used as WebAuthn.Config.Limiter key. Defaults to c.IP(), which honours
+	// fiber.Config.ProxyHeader.
+	ClientIP func(c *fiber.Ctx) string
+}
+
+// Mount registers POST /register/begin, /register/finish, /login/begin and /login/finish on the router,
+// e.g., app.Group("/webauthn"). Registrations by users with a session add a credential to their account.
+func Mount(router fiber.Router, config Config) error {
+	if config.Sessions == nil {
+		return ErrNilSessions
+	}
+	if config.ClientIP == nil {
+		config.ClientIP = func(c *fiber.Ctx) string { return c.IP() }
+	}
+	h, err := httpapi.New(httpapi.Config{
+		WebAuthn:     config.WebAuthn,
+		Users:        config.Users,
+		Credentials:  config.Credentials,
+		Challenges:   config.Challenges,
+		MaxBodyBytes: config.MaxBodyBytes,
+		OnRegistered: func(_ http.ResponseWriter, r *http.Request, user webauthn.UserEntity, result *webauthn.RegistrationResult) error {
+			if config.OnUserCreated == nil {
+				return nil
+			}
+			return config.OnUserCreated(fiberCtx(r), user, result)
+		},
+		OnCredentialAdded: func(_ http.ResponseWriter, r *http.Request, user webauthn.UserEntity, result *webauthn.RegistrationResult) error {
+			if config.OnCredentialAdded == nil {
+				return nil
+			}
+			return config.OnCredentialAdded(fiberCtx(r), user, result)
+		},
+		CurrentUser: func(r *http.Request) (*webauthn.UserEntity, error) {
+			user, err := config.Sessions.Validate(fiberCtx(r))
+			if errors.Is(err, ErrInvalidSession) {
+				return nil, nil
+			}
+			return user, err
+		},
+		OnLogin: func(_ http.ResponseWriter, r *http.Request, user webauthn.UserEntity, result *webauthn.LoginResult) error {
+			return config.Sessions.Issue(fiberCtx(r), user, result)
+		},
+		OnError: func(r *http.Request, err *httpapi.Error) {
+			if config.OnError != nil {
+				config.OnError(fiberCtx(r), err)
+			}
+		},
+		ClientIP: func(r *http.Request) string {
+			return config.ClientIP(fiberCtx(r))
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for path, endpoint := range h.Endpoints() {
+		handler := adaptor.HTTPHandler(endpoint)
+		router.Post(path, func(c *fiber.Ctx) error {
+			c.Locals(fiberCtxKey{}, c)
+			return handler(c)
+		})
+	}
+	return nil
+}
+
+// fiberCtx returns the *fiber.Ctx of a request converted by the adaptor, whose context is the fasthttp request.
+func fiberCtx(r *http.Request) *fiber.Ctx {
+	c, _ := r.Context().Value(fiberCtxKey{}).(*fiber.Ctx)
+	return c
+}
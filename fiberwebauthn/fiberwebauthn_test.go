@@ -0,0 +1,159 @@
+package fiberwebauthn
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/httpapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+func newTestWebAuthn(t *testing.T) *webauthn.WebAuthn {
+	t.Helper()
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:             "example.com",
+		RPDisplayName:    "Example",
+		RPOrigins:        []string{"https://example.com"},
+		Timeout:          60000,
+		UserVerification: webauthn.UVPreferred,
+		Attestation:      webauthn.AttestationNone,
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New: %v", err)
+	}
+	return w
+}
+
+// testRequest sends a request to the app and decodes the JSON response body.
+func testRequest(t *testing.T, app *fiber.App, req *http.Request) (*http.Response, map[string]any) {
+	t.Helper()
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	var out map[string]any
+	if len(body) > 0 && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(body, &out); err != nil {
+			t.Fatalf("Unmarshal %s: %v", body, err)
+		}
+	}
+	return resp, out
+}
+
+func postJSON(path, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// errorCode returns the code of an httpapi JSON error body.
+func errorCode(body map[string]any) string {
+	apiErr, _ := body["error"].(map[string]any)
+	code, _ := apiErr["code"].(string)
+	return code
+}
+
+func TestMountNilSessions(t *testing.T) {
+	store := httpapi.NewMemoryStore()
+	err := Mount(fiber.New(), Config{WebAuthn: newTestWebAuthn(t), Users: store, Credentials: store})
+	if !errors.Is(err, ErrNilSessions) {
+		t.Fatalf("Mount() error = %v, want %v", err, ErrNilSessions)
+	}
+	err = Mount(fiber.New(), Config{Users: store, Credentials: store, Sessions: NewMemorySessions()})
+	if !errors.Is(err, httpapi.ErrNilWebAuthn) {
+		t.Fatalf("Mount() without WebAuthn error = %v, want %v", err, httpapi.ErrNilWebAuthn)
+	}
+}
+
+func TestMountRoutes(t *testing.T) {
+	store := httpapi.NewMemoryStore()
+	var errorPaths []string
+	app := fiber.New()
+	err := Mount(app.Group("/webauthn"), Config{
+		WebAuthn:    newTestWebAuthn(t),
+		Users:       store,
+		Credentials: store,
+		Sessions:    NewMemorySessions(),
+		OnError: func(c *fiber.Ctx, err *httpapi.Error) {
+			errorPaths = append(errorPaths, strings.Clone(c.Path())) // Fiber reuses the buffer after the handler
+		},
+	})
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   string // Error code, empty for options
+	}{
+		{"register begin", postJSON("/webauthn/register/begin", `{"name": "alice"}`), http.StatusOK, ""},
+		{"login begin", postJSON("/webauthn/login/begin", ``), http.StatusOK, ""},
+		{"register finish", postJSON("/webauthn/register/finish", `{}`), http.StatusBadRequest, httpapi.CodeInvalidRequest},
+		{"login finish", postJSON("/webauthn/login/finish", `{}`), http.StatusBadRequest, httpapi.CodeInvalidRequest},
+		{"outside the group", postJSON("/register/begin", `{"name": "alice"}`), http.StatusNotFound, ""},
+		{"GET", httptest.NewRequest(http.MethodGet, "/webauthn/login/begin", nil), http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := testRequest(t, app, tt.req)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d (%v)", resp.StatusCode, tt.status, body)
+			}
+			if code := errorCode(body); code != tt.code {
+				t.Errorf("error code = %q, want %q", code, tt.code)
+			}
+			if tt.status == http.StatusOK && body["challenge"] == nil {
+				t.Errorf("response %v has no challenge", body)
+			}
+		})
+	}
+	if len(errorPaths) != 2 || errorPaths[0] != "/webauthn/register/finish" || errorPaths[1] != "/webauthn/login/finish" {
+		t.Errorf("OnError got the paths %v, want both finish endpoints", errorPaths)
+	}
+}
+
+// Registrations by users with a session add a credential to their account, which CurrentUser gets from Sessions.
+func TestMountCurrentUser(t *testing.T) {
+	store := httpapi.NewMemoryStore()
+	sessions := NewMemorySessions()
+	user := webauthn.UserEntity{ID: []byte{1, 2, 3}, Name: "alice", DisplayName: "Alice"}
+	app := fiber.New()
+	app.Post("/test-login", func(c *fiber.Ctx) error {
+		return sessions.Issue(c, user, &webauthn.LoginResult{})
+	})
+	if err := Mount(app.Group("/webauthn"), Config{WebAuthn: newTestWebAuthn(t), Users: store, Credentials: store, Sessions: sessions}); err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	resp, _ := testRequest(t, app, httptest.NewRequest(http.MethodPost, "/test-login", nil))
+	cookie := resp.Cookies()[0]
+
+	// No name needed, the options are for the signed-in user
+	req := postJSON("/webauthn/register/begin", ``)
+	req.AddCookie(cookie)
+	resp, body := testRequest(t, app, req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d (%v)", resp.StatusCode, http.StatusOK, body)
+	}
+	if got, _ := body["user"].(map[string]any)["name"].(string); got != user.Name {
+		t.Errorf("options are for user %q, want %q", got, user.Name)
+	}
+
+	// Without the session the name is required again
+	resp, body = testRequest(t, app, postJSON("/webauthn/register/begin", ``))
+	if resp.StatusCode != http.StatusBadRequest || errorCode(body) != httpapi.CodeInvalidRequest {
+		t.Errorf("without session status = %d, body %v, want %d", resp.StatusCode, body, http.StatusBadRequest)
+	}
+}
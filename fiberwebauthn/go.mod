@@ -0,0 +1,28 @@
+module github.com/MrBoombastic/WebAuthn2Go/fiberwebauthn
+
+go 1.23.0
+
+require (
+	github.com/MrBoombastic/WebAuthn2Go v0.0.0-00010101000000-000000000000
+	github.com/gofiber/fiber/v2 v2.52.6
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/fxamacker/cbor/v2 v2.8.0 // indirect
+	github.com/go-webauthn/webauthn v0.12.3 // indirect
+	github.com/google/go-tpm v0.9.3 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/mattn/go-runewidth v0.0.16 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.51.0 // indirect
+	github.com/valyala/tcplisten v1.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+)
+
+replace github.com/MrBoombastic/WebAuthn2Go => ../
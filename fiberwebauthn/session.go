@@ -0,0 +1,129 @@
+package fiberwebauthn
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/httpapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultSessionCookie = "webauthn_session"
+	defaultSessionTTL    = 12 * time.Hour
+)
+
+var ErrInvalidSession = errors.New("missing or invalid session")
+
+// userLocalsKey stores the session user in the fiber locals.
+type userLocalsKey struct{}
+
+// SessionManager issues sessions after logins and validates them on later requests.
+type SessionManager interface {
+	// Issue starts a session for the user, e.g., by setting a cookie.
+	Issue(c *fiber.Ctx, user webauthn.UserEntity, result *webauthn.LoginResult) error
+	// Validate returns the user of the request's session, or ErrInvalidSession.
+	Validate(c *fiber.Ctx) (*webauthn.UserEntity, error)
+}
+
+// RequireWebAuthnSession rejects requests without a valid session with 401 and the httpapi JSON error body.
+// Handlers after it get the user with SessionUser.
+func RequireWebAuthnSession(sessions SessionManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := sessions.Validate(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": &httpapi.Error{Code: httpapi.CodeUnauthorized, Message: "Login required"},
+			})
+		}
+		c.Locals(userLocalsKey{}, user)
+		return c.Next()
+	}
+}
+
+// SessionUser returns the user set by RequireWebAuthnSession, or nil.
+func SessionUser(c *fiber.Ctx) *webauthn.UserEntity {
+	user, _ := c.Locals(userLocalsKey{}).(*webauthn.UserEntity)
+	return user
+}
+
+// MemorySessions is an in-memory SessionManager using random session IDs in a cookie.
+// Sessions are lost on restart and not shared between instances. Expired sessions are dropped on every Issue.
+type MemorySessions struct {
+	CookieName string        // Defaults to "webauthn_session"
+	TTL        time.Duration // Defaults to 12 hours
+	mu         sync.Mutex
+	sessions   map[string]memorySession
+}
+
+type memorySession struct {
+	user      webauthn.UserEntity
+	expiresAt time.Time
+}
+
+// NewMemorySessions creates an empty MemorySessions with the default cookie name and TTL.
+func NewMemorySessions() *MemorySessions {
+	return &MemorySessions{
+		CookieName: defaultSessionCookie,
+		TTL:        defaultSessionTTL,
+		sessions:   make(map[string]memorySession),
+	}
+}
+
+func (s *MemorySessions) Issue(c *fiber.Ctx, user webauthn.UserEntity, _ *webauthn.LoginResult) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+	expiresAt := time.Now().Add(s.TTL)
+
+	s.mu.Lock()
+	// Drop expired sessions, sessions that are never used again would pile up otherwise
+	now := time.Now()
+	for stored, session := range s.sessions {
+		if now.After(session.expiresAt) {
+			delete(s.sessions, stored)
+		}
+	}
+	s.sessions[id] = memorySession{user: user, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	c.Cookie(&fiber.Cookie{
+		Name:     s.CookieName,
+		Value:    id,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return nil
+}
+
+func (s *MemorySessions) Validate(c *fiber.Ctx) (*webauthn.UserEntity, error) {
+	id := c.Cookies(s.CookieName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+	if time.Now().After(session.expiresAt) {
+		delete(s.sessions, id)
+		return nil, ErrInvalidSession
+	}
+	return &session.user, nil
+}
+
+// Revoke ends the request's session and clears the cookie, e.g., on logout.
+func (s *MemorySessions) Revoke(c *fiber.Ctx) {
+	s.mu.Lock()
+	delete(s.sessions, c.Cookies(s.CookieName))
+	s.mu.Unlock()
+	c.ClearCookie(s.CookieName)
+}
@@ -0,0 +1,113 @@
+package fiberwebauthn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/httpapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newSessionApp serves POST /login, issuing a session for user, and GET /private, guarded by RequireWebAuthnSession.
+func newSessionApp(sessions *MemorySessions, user webauthn.UserEntity) *fiber.App {
+	app := fiber.New()
+	app.Post("/login", func(c *fiber.Ctx) error {
+		return sessions.Issue(c, user, &webauthn.LoginResult{})
+	})
+	app.Get("/private", RequireWebAuthnSession(sessions), func(c *fiber.Ctx) error {
+		return c.SendString(SessionUser(c).Name)
+	})
+	return app
+}
+
+func TestRequireWebAuthnSession(t *testing.T) {
+	sessions := NewMemorySessions()
+	app := newSessionApp(sessions, webauthn.UserEntity{ID: []byte{1}, Name: "alice"})
+	resp, _ := testRequest(t, app, httptest.NewRequest(http.MethodPost, "/login", nil))
+	cookies := resp.Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	cookie := cookies[0]
+	if cookie.Name != defaultSessionCookie || !cookie.Secure || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie attributes = %+v", cookie)
+	}
+
+	tampered := *cookie
+	tampered.Value = cookie.Value[:len(cookie.Value)-1] + "A"
+	if tampered.Value == cookie.Value {
+		tampered.Value = cookie.Value[:len(cookie.Value)-1] + "B"
+	}
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		status int
+	}{
+		{"valid", cookie, http.StatusOK},
+		{"missing", nil, http.StatusUnauthorized},
+		{"tampered", &tampered, http.StatusUnauthorized},
+		{"other cookie name", &http.Cookie{Name: "session", Value: cookie.Value}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/private", nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			resp, body := testRequest(t, app, req)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && errorCode(body) != httpapi.CodeUnauthorized {
+				t.Errorf("error body = %v, want code %s", body, httpapi.CodeUnauthorized)
+			}
+		})
+	}
+
+	// Revoked sessions are rejected
+	app.Post("/logout", func(c *fiber.Ctx) error {
+		sessions.Revoke(c)
+		return nil
+	})
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(cookie)
+	testRequest(t, app, req)
+	req = httptest.NewRequest(http.MethodGet, "/private", nil)
+	req.AddCookie(cookie)
+	if resp, _ := testRequest(t, app, req); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status after Revoke = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestMemorySessionsExpiry(t *testing.T) {
+	sessions := NewMemorySessions()
+	sessions.TTL = time.Hour
+	app := newSessionApp(sessions, webauthn.UserEntity{ID: []byte{1}, Name: "alice"})
+	resp, _ := testRequest(t, app, httptest.NewRequest(http.MethodPost, "/login", nil))
+	cookie := resp.Cookies()[0]
+
+	// Let the session expire and add a stale one that is never used again
+	sessions.mu.Lock()
+	stored := sessions.sessions[cookie.Value]
+	stored.expiresAt = time.Now().Add(-time.Second)
+	sessions.sessions[cookie.Value] = stored
+	sessions.sessions["abandoned"] = memorySession{expiresAt: time.Now().Add(-time.Second)}
+	sessions.mu.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/private", nil)
+	req.AddCookie(cookie)
+	if resp, _ := testRequest(t, app, req); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status of an expired session = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	// Issuing drops expired sessions
+	testRequest(t, app, httptest.NewRequest(http.MethodPost, "/login", nil))
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	if _, ok := sessions.sessions["abandoned"]; ok || len(sessions.sessions) != 1 {
+		t.Fatalf("sessions after Issue = %d, want only the new one", len(sessions.sessions))
+	}
+}
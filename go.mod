@@ -5,12 +5,12 @@ go 1.23.0
 toolchain go1.24.1
 
 require (
+	github.com/fxamacker/cbor/v2 v2.8.0
 	github.com/go-webauthn/webauthn v0.12.3
 	github.com/google/uuid v1.6.0
 )
 
 require (
-	github.com/fxamacker/cbor/v2 v2.8.0 // indirect
 	github.com/google/go-tpm v0.9.3 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/sys v0.31.0 // indirect
@@ -0,0 +1,52 @@
+package httpapi
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNilWebAuthn        = errors.New("webauthn instance cannot be nil")
+	ErrNilUserStore       = errors.New("user store cannot be nil")
+	ErrNilCredentialStore = errors.New("credential store cannot be nil")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("user already exists")
+	ErrCredentialNotFound = errors.New("credential not found")
+	ErrChallengeNotFound  = errors.New("challenge not found or expired")
+)
+
+// Error codes of the JSON error bodies.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeRequestTooLarge    = "request_too_large"
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeUserExists         = "user_exists"
+	CodeChallengeNotFound  = "challenge_not_found"
+	CodeVerificationFailed = "verification_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeRateLimited        = "rate_limited"
+	CodeInternal           = "internal_error"
+)
+
+// Error is returned to clients as {"error": {"code": ..., "message": ...}}.
+// Err holds the cause, it's passed to Config.OnError but never sent to clients.
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+	// RetryAfter is sent in the Retry-After header, if set.
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
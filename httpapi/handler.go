@@ -0,0 +1,541 @@
+// Package httpapi serves the WebAuthn registration and login ceremonies over net/http, so any router can mount them.
+// Users, credentials and ceremony state are kept in pluggable stores. Signed-in users (see Config.CurrentUser)
+// register additional credentials through the same endpoints.
+//
+// Endpoints (all POST, JSON bodies):
+//
+//	/register/begin   {"name": "...", "displayName": "..."}  -> creation options (no body for signed-in users)
+//	/register/finish  PublicKeyCredential                     -> {"success": true, "credentialID": ...}
+//	/login/begin      {"name": "..."} (empty for passkeys)    -> request options
+//	/login/finish     PublicKeyCredentialAssertion            -> {"success": true, "userVerified": ...}
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/utils"
+	"github.com/google/uuid"
+)
+
+// defaultMaxBodyBytes limits request bodies, attestation objects with certificate chains stay well below it.
+const defaultMaxBodyBytes = 64 << 10
+
+// Config configures the Handler.
+type Config struct {
+	WebAuthn    *webauthn.WebAuthn
+	Users       UserStore
+	Credentials CredentialStore
+	Challenges  ChallengeStore // Defaults to a MemoryStore
+	// MaxBodyBytes limits request bodies, 64 KiB by default.
+	MaxBodyBytes int64
+	// OnRegistered is called after a successful registration created the user, e.g., to issue a session. Optional.
+	OnRegistered func(w http.ResponseWriter, r *http.Request, user webauthn.UserEntity, result *webauthn.RegistrationResult) error
+	// OnCredentialAdded is called after a signed-in user registered an additional credential. Optional.
+	OnCredentialAdded func(w http.ResponseWriter, r *http.Request, user webauthn.UserEntity, result *webauthn.RegistrationResult) error
+	// CurrentUser returns the signed-in user of the request, or nil. For signed-in users, /register/begin adds a
+	// credential to their account instead of creating a user. Optional, without it only new users can register.
+	CurrentUser func(r *http.Request) (*webauthn.UserEntity, error)
+	// OnLogin is called after a successful login, e.g., to issue a session. Optional.
+	OnLogin func(w http.ResponseWriter, r *http.Request, user webauthn.UserEntity, result *webauthn.LoginResult) error
+	// OnError is called with every error sent to a client, e.g., for logging. Optional.
+	OnError func(r *http.Request, err *Error)
+	// ClientIP returns the client address used as WebAuthn.Config.Limiter key. Defaults to the host of
+	// r.RemoteAddr, replace it to read e.g. X-Forwarded-For set by your trusted proxy.
+	ClientIP func(r *http.Request) string
+}
+
+// Handler serves the four ceremony endpoints, see the package documentation.
+type Handler struct {
+	config Config
+}
+
+// New creates a Handler. WebAuthn, Users and Credentials are required.
+func New(config Config) (*Handler, error) {
+	if config.WebAuthn == nil {
+		return nil, ErrNilWebAuthn
+	}
+	if config.Users == nil {
+		return nil, ErrNilUserStore
+	}
+	if config.Credentials == nil {
+		return nil, ErrNilCredentialStore
+	}
+	if config.Challenges == nil {
+		config.Challenges = NewMemoryStore()
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if config.ClientIP == nil {
+		config.ClientIP = remoteIP
+	}
+	return &Handler{config: config}, nil
+}
+
+// handleFunc handles a ceremony endpoint, returning the JSON response.
+type handleFunc func(w http.ResponseWriter, r *http.Request, body []byte) (any, error)
+
+// endpoints returns the handle functions by path.
+func (h *Handler) endpoints() map[string]handleFunc {
+	return map[string]handleFunc{
+		"/register/begin":  h.beginRegistration,
+		"/register/finish": h.finishRegistration,
+		"/login/begin":     h.beginLogin,
+		"/login/finish":    h.finishLogin,
+	}
+}
+
+// ServeHTTP routes the request to the ceremony endpoint. Mount it with http.StripPrefix under a path prefix.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handle, ok := h.endpoints()[r.URL.Path]
+	if !ok {
+		h.writeError(w, r, &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "Not found"})
+		return
+	}
+	h.serve(w, r, handle)
+}
+
+// Endpoints returns a handler for every endpoint by path (e.g., "/login/begin"),
+// for routers that register routes one by one.
+func (h *Handler) Endpoints() map[string]http.Handler {
+	handlers := make(map[string]http.Handler)
+	for path, handle := range h.endpoints() {
+		handlers[path] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.serve(w, r, handle)
+		})
+	}
+	return handlers
+}
+
+// serve reads the body within the size limit, calls handle and writes its response or error.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, handle handleFunc) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeError(w, r, &Error{Status: http.StatusMethodNotAllowed, Code: CodeMethodNotAllowed, Message: "Method not allowed"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.config.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeError(w, r, &Error{Status: http.StatusRequestEntityTooLarge, Code: CodeRequestTooLarge, Message: "Request body too large", Err: err})
+			return
+		}
+		h.writeError(w, r, badRequest("Failed to read request body", err))
+		return
+	}
+
+	response, err := handle(w, r, body)
+	if err != nil {
+		var apiErr *Error
+		if !errors.As(err, &apiErr) {
+			apiErr = internalError("Internal error", err)
+		}
+		h.writeError(w, r, apiErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// /register/begin
+func (h *Handler) beginRegistration(_ http.ResponseWriter, r *http.Request, body []byte) (any, error) {
+	current, err := h.currentUser(r)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return h.beginAddCredential(r, *current)
+	}
+
+	var req struct {
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, badRequest("Failed to parse request body", err)
+	}
+	if req.Name == "" {
+		return nil, badRequest("Name is required", nil)
+	}
+	if req.DisplayName == "" {
+		req.DisplayName = req.Name
+	}
+
+	// Users are only created once the registration finishes, this is just a quick check
+	if _, err := h.config.Users.UserByName(r.Context(), req.Name); err == nil {
+		return nil, userExists()
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return nil, internalError("Failed to look up user", err)
+	}
+
+	userID := uuid.New()
+	user := webauthn.UserEntity{ID: userID[:], Name: req.Name, DisplayName: req.DisplayName}
+	opts, err := h.config.WebAuthn.BeginRegistration(user)
+	if err != nil {
+		return nil, internalError("Failed to begin registration", err)
+	}
+	if err := h.saveSession(r.Context(), opts.Challenge, Session{User: user, Registration: opts}); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// beginAddCredential starts registering another credential for the signed-in user.
+// Their existing credentials are excluded, so the same authenticator isn't registered twice.
+func (h *Handler) beginAddCredential(r *http.Request, user webauthn.UserEntity) (any, error) {
+	creds, err := h.config.Credentials.CredentialsByUser(r.Context(), user.ID)
+	if err != nil {
+		return nil, internalError("Failed to look up credentials", err)
+	}
+	credentialIDs, transports := credentialHints(creds)
+	opts, err := h.config.WebAuthn.BeginRegistration(user,
+		webauthn.WithExcludeCredentials(credentialIDs...), webauthn.WithCredentialTransports(transports))
+	if err != nil {
+		return nil, internalError("Failed to begin registration", err)
+	}
+	if err := h.saveSession(r.Context(), opts.Challenge, Session{User: user, Registration: opts, ExistingUser: true}); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// /register/finish
+func (h *Handler) finishRegistration(w http.ResponseWriter, r *http.Request, body []byte) (any, error) {
+	var payload webauthn.PublicKeyCredential
+	if err := payload.Parse(body); err != nil {
+		return nil, badRequest("Failed to parse request body", err)
+	}
+	session, err := h.takeSession(r.Context(), payload.ClientData().Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if session.Registration == nil {
+		return nil, challengeNotFound(nil)
+	}
+	if session.ExistingUser {
+		// The user who started adding the credential must still be signed in
+		current, err := h.currentUser(r)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || !bytes.Equal(current.ID, session.User.ID) {
+			return nil, unauthorized()
+		}
+	}
+
+	result, err := h.config.WebAuthn.FinishRegistration(payload.RegistrationData(session.Registration))
+	if err != nil {
+		return nil, verificationFailed("Registration verification failed", err)
+	}
+
+	cred := Credential{
+		ID:         result.CredentialID,
+		UserID:     session.User.ID,
+		PublicKey:  result.PublicKey,
+		SignCount:  result.SignCount,
+		Transports: result.Transports,
+	}
+	onRegistered := h.config.OnRegistered
+	if session.ExistingUser {
+		if err := h.config.Credentials.AddCredential(r.Context(), cred); err != nil {
+			return nil, internalError("Failed to store credential", err)
+		}
+		onRegistered = h.config.OnCredentialAdded
+	} else if err := h.createUser(r.Context(), session.User, cred); err != nil {
+		return nil, err
+	}
+	if onRegistered != nil {
+		if err := onRegistered(w, r, session.User, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]any{
+		"success":           true,
+		"credentialID":      result.CredentialID,
+		"authenticatorName": result.AuthenticatorName,
+		"aaguid":            result.AAGUID,
+	}, nil
+}
+
+// /login/begin
+func (h *Handler) beginLogin(_ http.ResponseWriter, r *http.Request, body []byte) (any, error) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, badRequest("Failed to parse request body", err)
+		}
+	}
+
+	var (
+		session Session
+		opts    *webauthn.PublicKeyCredentialRequestOptions
+		err     error
+	)
+	if err := h.checkLimits(webauthn.LimitKey{Kind: webauthn.LimitIP, Value: h.config.ClientIP(r)}); err != nil {
+		return nil, err
+	}
+	if req.Name == "" {
+		// Discoverable credential (passkey) login, the user is identified at finish
+		opts, err = h.config.WebAuthn.BeginLogin(nil)
+	} else {
+		var (
+			credentialIDs []string
+			transports    map[string][]webauthn.AuthenticatorTransport
+			user          *webauthn.UserEntity
+		)
+		user, err = h.config.Users.UserByName(r.Context(), req.Name)
+		switch {
+		case err == nil:
+			session.User = *user
+			// A lockout would reveal that the user exists, so it's only checked at finish if that is hidden
+			if len(h.config.WebAuthn.Config.FakeCredentialSecret) == 0 {
+				if err := h.checkLimits(webauthn.LimitKey{Kind: webauthn.LimitUser, Value: base64.RawURLEncoding.EncodeToString(user.ID)}); err != nil {
+					return nil, err
+				}
+			}
+			creds, err := h.config.Credentials.CredentialsByUser(r.Context(), user.ID)
+			if err != nil {
+				return nil, internalError("Failed to look up credentials", err)
+			}
+			credentialIDs, transports = credentialHints(creds)
+		case !errors.Is(err, ErrUserNotFound):
+			return nil, internalError("Failed to look up user", err)
+		}
+		opts, err = h.beginLoginForUser(req.Name, credentialIDs, webauthn.WithCredentialTransports(transports))
+	}
+	if err != nil {
+		return nil, internalError("Failed to begin login", err)
+	}
+
+	session.Login = opts
+	if err := h.saveSession(r.Context(), opts.Challenge, session); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// beginLoginForUser hides whether the user exists if fake credentials are configured.
+func (h *Handler) beginLoginForUser(name string, credentialIDs []string, opts ...webauthn.Option) (*webauthn.PublicKeyCredentialRequestOptions, error) {
+	if len(h.config.WebAuthn.Config.FakeCredentialSecret) > 0 {
+		return h.config.WebAuthn.BeginLoginForUser(name, credentialIDs, opts...)
+	}
+	return h.config.WebAuthn.BeginLogin(credentialIDs, opts...)
+}
+
+// credentialHints returns the IDs and stored transports of the credentials, for allow and exclude lists.
+func credentialHints(creds []Credential) ([]string, map[string][]webauthn.AuthenticatorTransport) {
+	credentialIDs := make([]string, 0, len(creds))
+	transports := make(map[string][]webauthn.AuthenticatorTransport, len(creds))
+	for _, cred := range creds {
+		credentialIDs = append(credentialIDs, cred.ID)
+		transports[cred.ID] = cred.Transports
+	}
+	return credentialIDs, transports
+}
+
+// /login/finish
+func (h *Handler) finishLogin(w http.ResponseWriter, r *http.Request, body []byte) (any, error) {
+	var payload webauthn.PublicKeyCredentialAssertion
+	if err := payload.Parse(body); err != nil {
+		return nil, badRequest("Failed to parse request body", err)
+	}
+	session, err := h.takeSession(r.Context(), payload.GetChallenge())
+	if err != nil {
+		return nil, err
+	}
+	if session.Login == nil {
+		return nil, challengeNotFound(nil)
+	}
+
+	cred, err := h.config.Credentials.CredentialByID(r.Context(), payload.ID)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return nil, verificationFailed("Login verification failed", err)
+	} else if err != nil {
+		return nil, internalError("Failed to look up credential", err)
+	}
+	// The credential must belong to the user who started the login, or to the user the authenticator returned
+	if session.User.ID != nil && !bytes.Equal(cred.UserID, session.User.ID) {
+		return nil, verificationFailed("Login verification failed", ErrCredentialNotFound)
+	}
+	if payload.UserHandle != "" {
+		userHandle, err := utils.DecodeBase64URL(payload.UserHandle)
+		if err != nil || !bytes.Equal(userHandle, cred.UserID) {
+			return nil, verificationFailed("Login verification failed", webauthn.ErrUserHandleMismatch)
+		}
+	}
+
+	result, err := h.config.WebAuthn.FinishLogin(&webauthn.LoginData{
+		ClientDataJSON:         payload.ClientDataJSON,
+		AuthData:               payload.AuthenticatorData,
+		Signature:              payload.Signature,
+		StoredSignCount:        cred.SignCount,
+		PublicKey:              cred.PublicKey,
+		CredentialID:           cred.ID,
+		UserHandle:             payload.UserHandle,
+		ClientExtensionResults: payload.ClientExtensionResults,
+		Options:                session.Login,
+		UserID:                 cred.UserID,
+		ClientIP:               h.config.ClientIP(r),
+	})
+	if errors.Is(err, webauthn.ErrRateLimited) {
+		return nil, rateLimited(err)
+	} else if err != nil {
+		return nil, verificationFailed("Login verification failed", err)
+	}
+	result.CredentialID = cred.ID
+	result.UserID = cred.UserID
+
+	if err := h.config.Credentials.UpdateSignCount(r.Context(), cred.ID, result.NewSignCount); err != nil {
+		return nil, internalError("Failed to update sign count", err)
+	}
+	user, err := h.config.Users.UserByID(r.Context(), cred.UserID)
+	if err != nil {
+		return nil, internalError("Failed to look up user", err)
+	}
+	if h.config.OnLogin != nil {
+		if err := h.config.OnLogin(w, r, *user, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]any{
+		"success":      true,
+		"userVerified": result.UserVerified,
+		"name":         user.Name,
+		"displayName":  user.DisplayName,
+	}, nil
+}
+
+// createUser stores the new user with their first credential. The user is deleted again if the credential can't be
+// stored, a user without credentials could never log in nor register again.
+func (h *Handler) createUser(ctx context.Context, user webauthn.UserEntity, cred Credential) error {
+	if err := h.config.Users.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, ErrUserExists) {
+			return userExists()
+		}
+		return internalError("Failed to create user", err)
+	}
+	if err := h.config.Credentials.AddCredential(ctx, cred); err != nil {
+		if deleteErr := h.config.Users.DeleteUser(ctx, user.ID); deleteErr != nil {
+			err = errors.Join(err, deleteErr)
+		}
+		return internalError("Failed to store credential", err)
+	}
+	return nil
+}
+
+// currentUser returns the signed-in user, or nil if there is none or Config.CurrentUser isn't set.
+func (h *Handler) currentUser(r *http.Request) (*webauthn.UserEntity, error) {
+	if h.config.CurrentUser == nil {
+		return nil, nil
+	}
+	user, err := h.config.CurrentUser(r)
+	if err != nil {
+		return nil, internalError("Failed to look up signed-in user", err)
+	}
+	return user, nil
+}
+
+// checkLimits rejects requests for locked out keys.
+func (h *Handler) checkLimits(keys ...webauthn.LimitKey) error {
+	err := h.config.WebAuthn.CheckLimits(keys...)
+	if errors.Is(err, webauthn.ErrRateLimited) {
+		return rateLimited(err)
+	} else if err != nil {
+		return internalError("Failed to check limits", err)
+	}
+	return nil
+}
+
+// saveSession stores the session until the ceremony times out.
+func (h *Handler) saveSession(ctx context.Context, challenge string, session Session) error {
+	expiresAt := time.Now().Add(time.Duration(h.config.WebAuthn.Config.Timeout) * time.Millisecond)
+	if err := h.config.Challenges.Save(ctx, challenge, session, expiresAt); err != nil {
+		return internalError("Failed to save challenge", err)
+	}
+	return nil
+}
+
+// takeSession consumes the session of the challenge.
+func (h *Handler) takeSession(ctx context.Context, challenge string) (*Session, error) {
+	session, err := h.config.Challenges.Take(ctx, challenge)
+	if errors.Is(err, ErrChallengeNotFound) {
+		return nil, challengeNotFound(err)
+	} else if err != nil {
+		return nil, internalError("Failed to load challenge", err)
+	}
+	return session, nil
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err *Error) {
+	if h.config.OnError != nil {
+		h.config.OnError(r, err)
+	}
+	if err.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(err.RetryAfter.Seconds())+1))
+	}
+	writeJSON(w, err.Status, map[string]*Error{"error": err})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// remoteIP returns the host of r.RemoteAddr.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimited reports a lockout, asking the client to retry when it ends.
+func rateLimited(err error) *Error {
+	apiErr := &Error{Status: http.StatusTooManyRequests, Code: CodeRateLimited, Message: "Too many failed attempts, try again later", Err: err}
+	var lockout *webauthn.LockoutError
+	if errors.As(err, &lockout) {
+		apiErr.RetryAfter = time.Until(lockout.Until)
+	}
+	return apiErr
+}
+
+func badRequest(message string, err error) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: message, Err: err}
+}
+
+func userExists() *Error {
+	return &Error{Status: http.StatusConflict, Code: CodeUserExists, Message: "User already exists", Err: ErrUserExists}
+}
+
+func unauthorized() *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Login required"}
+}
+
+func challengeNotFound(err error) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeChallengeNotFound, Message: "Challenge not found or expired", Err: err}
+}
+
+func verificationFailed(message string, err error) *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: CodeVerificationFailed, Message: message, Err: err}
+}
+
+func internalError(message string, err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: message, Err: err}
+}
@@ -0,0 +1,517 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/fxamacker/cbor/v2"
+)
+
+const testOrigin = "https://example.com"
+
+var b64 = base64.RawURLEncoding
+
+// testAuthenticator is a software authenticator with a P-256 credential and "none" attestation.
+type testAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	id        []byte
+	signCount uint32
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &testAuthenticator{key: key, id: id}
+}
+
+func (a *testAuthenticator) credentialID() string {
+	return b64.EncodeToString(a.id)
+}
+
+// authData returns authenticator data with UP and UV set, and the attested credential if attested.
+func (a *testAuthenticator) authData(t *testing.T, attested bool) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte("example.com"))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := byte(0x05)
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.id)))
+		data = append(data, a.id...)
+		data = append(data, marshalCBOR(t, map[int]any{
+			1: 2, 3: -7, -1: 1,
+			-2: a.key.X.FillBytes(make([]byte, 32)),
+			-3: a.key.Y.FillBytes(make([]byte, 32)),
+		})...)
+	}
+	return data
+}
+
+func marshalCBOR(t *testing.T, v any) []byte {
+	t.Helper()
+	mode, err := cbor.CTAP2EncOptions().EncMode()
+	if err != nil {
+		t.Fatalf("EncMode: %v", err)
+	}
+	data, err := mode.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return data
+}
+
+func clientDataJSON(t *testing.T, ceremonyType, challenge string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{"type": ceremonyType, "challenge": challenge, "origin": testOrigin})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return data
+}
+
+// register returns the RegistrationResponseJSON for the challenge.
+func (a *testAuthenticator) register(t *testing.T, challenge string) string {
+	t.Helper()
+	attestationObject := marshalCBOR(t, map[string]any{"fmt": "none", "attStmt": map[string]any{}, "authData": a.authData(t, true)})
+	return mustJSON(t, map[string]any{
+		"id":    a.credentialID(),
+		"rawId": a.credentialID(),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64.EncodeToString(clientDataJSON(t, "webauthn.create", challenge)),
+			"attestationObject": b64.EncodeToString(attestationObject),
+			"transports":        []string{"usb"},
+		},
+	})
+}
+
+// assert returns the AuthenticationResponseJSON for the challenge, with the user handle if not nil.
+func (a *testAuthenticator) assert(t *testing.T, challenge string, userHandle []byte) string {
+	t.Helper()
+	a.signCount++
+	authData := a.authData(t, false)
+	clientData := clientDataJSON(t, "webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1: %v", err)
+	}
+	return mustJSON(t, map[string]any{
+		"id":   a.credentialID(),
+		"type": "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64.EncodeToString(clientData),
+			"authenticatorData": b64.EncodeToString(authData),
+			"signature":         b64.EncodeToString(signature),
+			"userHandle":        b64.EncodeToString(userHandle),
+		},
+	})
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return string(data)
+}
+
+func newTestWebAuthn(t *testing.T, limiter webauthn.Limiter) *webauthn.WebAuthn {
+	t.Helper()
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:             "example.com",
+		RPDisplayName:    "Example",
+		RPOrigins:        []string{testOrigin},
+		Timeout:          60000,
+		UserVerification: webauthn.UVPreferred,
+		Attestation:      webauthn.AttestationNone,
+		Limiter:          limiter,
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New: %v", err)
+	}
+	return w
+}
+
+// newTestHandler creates a Handler on a MemoryStore, configure may change the config first.
+func newTestHandler(t *testing.T, configure func(config *Config)) (*Handler, *MemoryStore) {
+	t.Helper()
+	store := NewMemoryStore()
+	config := Config{WebAuthn: newTestWebAuthn(t, nil), Users: store, Credentials: store}
+	if configure != nil {
+		configure(&config)
+	}
+	h, err := New(config)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return h, store
+}
+
+// post sends a POST request to the handler and decodes the JSON response into out, if not nil.
+func post(t *testing.T, h http.Handler, path, body string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("Unmarshal %s: %v", rec.Body, err)
+		}
+	}
+	return rec
+}
+
+// errorBody is the JSON error body written for every *Error.
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// wantError checks the status and error code of a response.
+func wantError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	var body errorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Unmarshal %s: %v", rec.Body, err)
+	}
+	if rec.Code != status || body.Error.Code != code {
+		t.Fatalf("response = %d %s, want %d %s", rec.Code, rec.Body, status, code)
+	}
+}
+
+// registerUser registers name with a new authenticator.
+func registerUser(t *testing.T, h http.Handler, name string) *testAuthenticator {
+	t.Helper()
+	var opts webauthn.BeginRegistrationOptions
+	if rec := post(t, h, "/register/begin", `{"name": "`+name+`"}`, &opts); rec.Code != http.StatusOK {
+		t.Fatalf("/register/begin = %d %s", rec.Code, rec.Body)
+	}
+	a := newTestAuthenticator(t)
+	if rec := post(t, h, "/register/finish", a.register(t, opts.Challenge), nil); rec.Code != http.StatusOK {
+		t.Fatalf("/register/finish = %d %s", rec.Code, rec.Body)
+	}
+	return a
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	var loggedIn *webauthn.LoginResult
+	h, store := newTestHandler(t, func(config *Config) {
+		config.OnLogin = func(_ http.ResponseWriter, _ *http.Request, _ webauthn.UserEntity, result *webauthn.LoginResult) error {
+			loggedIn = result
+			return nil
+		}
+	})
+
+	var opts webauthn.BeginRegistrationOptions
+	if rec := post(t, h, "/register/begin", `{"name": "alice", "displayName": "Alice"}`, &opts); rec.Code != http.StatusOK {
+		t.Fatalf("/register/begin = %d %s", rec.Code, rec.Body)
+	}
+	if opts.User.Name != "alice" || opts.User.DisplayName != "Alice" || len(opts.User.ID) != 16 {
+		t.Errorf("options user = %+v", opts.User)
+	}
+	// Users are only created once the registration finishes
+	if _, err := store.UserByName(context.Background(), "alice"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("UserByName() before finish error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	a := newTestAuthenticator(t)
+	registration := a.register(t, opts.Challenge)
+	var registered struct {
+		Success      bool   `json:"success"`
+		CredentialID string `json:"credentialID"`
+	}
+	if rec := post(t, h, "/register/finish", registration, &registered); rec.Code != http.StatusOK {
+		t.Fatalf("/register/finish = %d %s", rec.Code, rec.Body)
+	}
+	if !registered.Success || registered.CredentialID != a.credentialID() {
+		t.Errorf("/register/finish response = %+v", registered)
+	}
+	cred, err := store.CredentialByID(context.Background(), a.credentialID())
+	if err != nil {
+		t.Fatalf("CredentialByID: %v", err)
+	}
+	if !bytes.Equal(cred.UserID, opts.User.ID) || len(cred.Transports) != 1 || cred.Transports[0] != webauthn.TransportUSB {
+		t.Errorf("stored credential = %+v", cred)
+	}
+	// The challenge was consumed
+	wantError(t, post(t, h, "/register/finish", registration, nil), http.StatusBadRequest, CodeChallengeNotFound)
+	wantError(t, post(t, h, "/register/begin", `{"name": "alice"}`, nil), http.StatusConflict, CodeUserExists)
+
+	// Login by name, the allow list carries the credential and its transports
+	var loginOpts webauthn.PublicKeyCredentialRequestOptions
+	if rec := post(t, h, "/login/begin", `{"name": "alice"}`, &loginOpts); rec.Code != http.StatusOK {
+		t.Fatalf("/login/begin = %d %s", rec.Code, rec.Body)
+	}
+	if len(loginOpts.AllowCredentials) != 1 || loginOpts.AllowCredentials[0].ID != a.credentialID() || len(loginOpts.AllowCredentials[0].Transports) != 1 {
+		t.Errorf("allowCredentials = %+v", loginOpts.AllowCredentials)
+	}
+	assertion := a.assert(t, loginOpts.Challenge, nil)
+	var loggedInBody struct {
+		Success bool   `json:"success"`
+		Name    string `json:"name"`
+	}
+	if rec := post(t, h, "/login/finish", assertion, &loggedInBody); rec.Code != http.StatusOK {
+		t.Fatalf("/login/finish = %d %s", rec.Code, rec.Body)
+	}
+	if !loggedInBody.Success || loggedInBody.Name != "alice" {
+		t.Errorf("/login/finish response = %+v", loggedInBody)
+	}
+	if loggedIn == nil || loggedIn.CredentialID != a.credentialID() || !bytes.Equal(loggedIn.UserID, opts.User.ID) {
+		t.Errorf("OnLogin result = %+v", loggedIn)
+	}
+	if cred, _ := store.CredentialByID(context.Background(), a.credentialID()); cred.SignCount != 1 {
+		t.Errorf("stored sign count = %d, want 1", cred.SignCount)
+	}
+	// Replaying the assertion fails, its challenge was consumed
+	wantError(t, post(t, h, "/login/finish", assertion, nil), http.StatusBadRequest, CodeChallengeNotFound)
+
+	// Passkey login, the user is identified by the user handle
+	if rec := post(t, h, "/login/begin", ``, &loginOpts); rec.Code != http.StatusOK {
+		t.Fatalf("/login/begin = %d %s", rec.Code, rec.Body)
+	}
+	if len(loginOpts.AllowCredentials) != 0 {
+		t.Errorf("passkey allowCredentials = %+v, want none", loginOpts.AllowCredentials)
+	}
+	if rec := post(t, h, "/login/finish", a.assert(t, loginOpts.Challenge, opts.User.ID), nil); rec.Code != http.StatusOK {
+		t.Fatalf("passkey /login/finish = %d %s", rec.Code, rec.Body)
+	}
+	post(t, h, "/login/begin", ``, &loginOpts)
+	wantError(t, post(t, h, "/login/finish", a.assert(t, loginOpts.Challenge, []byte("other")), nil), http.StatusUnauthorized, CodeVerificationFailed)
+}
+
+func TestFinishRejects(t *testing.T) {
+	h, _ := newTestHandler(t, nil)
+	a := registerUser(t, h, "alice")
+	other := registerUser(t, h, "bob")
+
+	var regOpts webauthn.BeginRegistrationOptions
+	post(t, h, "/register/begin", `{"name": "carol"}`, &regOpts)
+	var loginOpts webauthn.PublicKeyCredentialRequestOptions
+	post(t, h, "/login/begin", `{"name": "alice"}`, &loginOpts)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"unknown registration challenge", "/register/finish", newTestAuthenticator(t).register(t, "unknown"), http.StatusBadRequest, CodeChallengeNotFound},
+		{"login challenge for registration", "/register/finish", newTestAuthenticator(t).register(t, loginOpts.Challenge), http.StatusBadRequest, CodeChallengeNotFound},
+		{"unknown login challenge", "/login/finish", a.assert(t, "unknown", nil), http.StatusBadRequest, CodeChallengeNotFound},
+		{"registration challenge for login", "/login/finish", a.assert(t, regOpts.Challenge, nil), http.StatusBadRequest, CodeChallengeNotFound},
+		{"malformed JSON", "/login/finish", `{`, http.StatusBadRequest, CodeInvalidRequest},
+		{"missing name", "/register/begin", `{}`, http.StatusBadRequest, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantError(t, post(t, h, tt.path, tt.body, nil), tt.status, tt.code)
+		})
+	}
+
+	// Another user's credential can't answer a login started for alice
+	post(t, h, "/login/begin", `{"name": "alice"}`, &loginOpts)
+	wantError(t, post(t, h, "/login/finish", other.assert(t, loginOpts.Challenge, nil), nil), http.StatusUnauthorized, CodeVerificationFailed)
+}
+
+func TestErrorBody(t *testing.T) {
+	var reported []*Error
+	h, _ := newTestHandler(t, func(config *Config) {
+		config.OnError = func(_ *http.Request, err *Error) { reported = append(reported, err) }
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/unknown", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("response = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if want := `{"error":{"code":"not_found","message":"Not found"}}` + "\n"; rec.Body.String() != want {
+		t.Errorf("body = %s, want %s", rec.Body, want)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login/begin", nil))
+	wantError(t, rec, http.StatusMethodNotAllowed, CodeMethodNotAllowed)
+	if allow := rec.Header().Get("Allow"); allow != http.MethodPost {
+		t.Errorf("Allow = %s, want POST", allow)
+	}
+
+	// The cause goes to OnError, never to the client
+	rec = post(t, h, "/register/begin", `{`, nil)
+	wantError(t, rec, http.StatusBadRequest, CodeInvalidRequest)
+	var raw map[string]map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(raw) != 1 || len(raw["error"]) != 2 {
+		t.Errorf("body = %s, want only the code and message", rec.Body)
+	}
+	if len(reported) != 3 || reported[2].Err == nil || reported[2].Status != http.StatusBadRequest {
+		t.Errorf("OnError got %+v", reported)
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	h, _ := newTestHandler(t, func(config *Config) { config.MaxBodyBytes = 32 })
+	for path := range h.Endpoints() {
+		t.Run(path, func(t *testing.T) {
+			wantError(t, post(t, h, path, `{"name": "`+strings.Repeat("a", 32)+`"}`, nil), http.StatusRequestEntityTooLarge, CodeRequestTooLarge)
+		})
+	}
+	if rec := post(t, h, "/register/begin", `{"name": "alice"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("/register/begin within the limit = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// failingCredentials fails AddCredential while fail is set.
+type failingCredentials struct {
+	*MemoryStore
+	fail bool
+}
+
+func (s *failingCredentials) AddCredential(ctx context.Context, cred Credential) error {
+	if s.fail {
+		return errors.New("database down")
+	}
+	return s.MemoryStore.AddCredential(ctx, cred)
+}
+
+func TestCreateUserRollback(t *testing.T) {
+	store := NewMemoryStore()
+	credentials := &failingCredentials{MemoryStore: store, fail: true}
+	registered := false
+	h, err := New(Config{
+		WebAuthn:    newTestWebAuthn(t, nil),
+		Users:       store,
+		Credentials: credentials,
+		OnRegistered: func(http.ResponseWriter, *http.Request, webauthn.UserEntity, *webauthn.RegistrationResult) error {
+			registered = true
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var opts webauthn.BeginRegistrationOptions
+	post(t, h, "/register/begin", `{"name": "alice"}`, &opts)
+	wantError(t, post(t, h, "/register/finish", newTestAuthenticator(t).register(t, opts.Challenge), nil), http.StatusInternalServerError, CodeInternal)
+	if _, err := store.UserByName(context.Background(), "alice"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("UserByName() after the failed registration error = %v, want %v", err, ErrUserNotFound)
+	}
+	if registered {
+		t.Errorf("OnRegistered called for a failed registration")
+	}
+
+	// The name is free again
+	credentials.fail = false
+	registerUser(t, h, "alice")
+	if _, err := store.UserByName(context.Background(), "alice"); err != nil {
+		t.Fatalf("UserByName: %v", err)
+	}
+}
+
+func TestAddCredential(t *testing.T) {
+	var current *webauthn.UserEntity
+	var added, registered int
+	h, store := newTestHandler(t, func(config *Config) {
+		config.CurrentUser = func(*http.Request) (*webauthn.UserEntity, error) { return current, nil }
+		config.OnCredentialAdded = func(http.ResponseWriter, *http.Request, webauthn.UserEntity, *webauthn.RegistrationResult) error {
+			added++
+			return nil
+		}
+		config.OnRegistered = func(http.ResponseWriter, *http.Request, webauthn.UserEntity, *webauthn.RegistrationResult) error {
+			registered++
+			return nil
+		}
+	})
+	first := registerUser(t, h, "alice")
+	user, err := store.UserByName(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("UserByName: %v", err)
+	}
+	current = user
+
+	// Signed in, no name is needed and the existing credential is excluded
+	var opts webauthn.BeginRegistrationOptions
+	if rec := post(t, h, "/register/begin", ``, &opts); rec.Code != http.StatusOK {
+		t.Fatalf("/register/begin = %d %s", rec.Code, rec.Body)
+	}
+	if !bytes.Equal(opts.User.ID, user.ID) {
+		t.Errorf("options user ID = %x, want %x", opts.User.ID, user.ID)
+	}
+	if len(opts.ExcludeCredentials) != 1 || opts.ExcludeCredentials[0].ID != first.credentialID() {
+		t.Errorf("excludeCredentials = %+v, want the first credential", opts.ExcludeCredentials)
+	}
+	second := newTestAuthenticator(t)
+	if rec := post(t, h, "/register/finish", second.register(t, opts.Challenge), nil); rec.Code != http.StatusOK {
+		t.Fatalf("/register/finish = %d %s", rec.Code, rec.Body)
+	}
+	if creds, _ := store.CredentialsByUser(context.Background(), user.ID); len(creds) != 2 {
+		t.Errorf("user has %d credentials, want 2", len(creds))
+	}
+	if added != 1 || registered != 1 {
+		t.Errorf("OnCredentialAdded called %d times and OnRegistered %d times, want 1 each", added, registered)
+	}
+
+	// The user must still be signed in at finish
+	post(t, h, "/register/begin", ``, &opts)
+	current = nil
+	wantError(t, post(t, h, "/register/finish", newTestAuthenticator(t).register(t, opts.Challenge), nil), http.StatusUnauthorized, CodeUnauthorized)
+}
+
+func TestRateLimited(t *testing.T) {
+	limiter := webauthn.NewMemoryLimiter(map[webauthn.LimitKind]webauthn.Limit{
+		webauthn.LimitIP: {Max: 2, Window: time.Minute, Lockout: time.Minute},
+	})
+	h, _ := newTestHandler(t, func(config *Config) { config.WebAuthn = newTestWebAuthn(t, limiter) })
+	a := registerUser(t, h, "alice")
+
+	var opts webauthn.PublicKeyCredentialRequestOptions
+	for range 2 {
+		post(t, h, "/login/begin", `{"name": "alice"}`, &opts)
+		// Signed by another key
+		forged := newTestAuthenticator(t)
+		forged.id = a.id
+		wantError(t, post(t, h, "/login/finish", forged.assert(t, opts.Challenge, nil), nil), http.StatusUnauthorized, CodeVerificationFailed)
+	}
+
+	rec := post(t, h, "/login/begin", `{"name": "alice"}`, nil)
+	wantError(t, rec, http.StatusTooManyRequests, CodeRateLimited)
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Retry-After = %q, want 60", retryAfter)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	if bytes.Contains(body, []byte("locked out until")) {
+		t.Errorf("body %s reveals the lockout error", body)
+	}
+}
@@ -0,0 +1,197 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+)
+
+// UserStore looks up and creates users.
+type UserStore interface {
+	// UserByName returns the user with the name, or ErrUserNotFound.
+	UserByName(ctx context.Context, name string) (*webauthn.UserEntity, error)
+	// UserByID returns the user with the ID, or ErrUserNotFound.
+	UserByID(ctx context.Context, id []byte) (*webauthn.UserEntity, error)
+	// CreateUser stores a new user after a successful registration, or returns ErrUserExists if the name is taken.
+	CreateUser(ctx context.Context, user webauthn.UserEntity) error
+	// DeleteUser removes the user with the ID. It rolls back a CreateUser whose first credential couldn't be stored,
+	// so the name can be registered again. It's called on an error path, so implementations must make it idempotent:
+	// deleting a user that doesn't exist (anymore) returns nil.
+	DeleteUser(ctx context.Context, id []byte) error
+}
+
+// Credential is a registered credential.
+type Credential struct {
+	ID        string // Base64url
+	UserID    []byte
+	PublicKey []byte // COSE
+	SignCount uint32
+	// Transports reported at registration, hints for allowCredentials
+	Transports []webauthn.AuthenticatorTransport
+}
+
+// CredentialStore stores registered credentials.
+type CredentialStore interface {
+	// AddCredential stores a new credential.
+	AddCredential(ctx context.Context, cred Credential) error
+	// CredentialsByUser returns all credentials of the user, possibly none.
+	CredentialsByUser(ctx context.Context, userID []byte) ([]Credential, error)
+	// CredentialByID returns the credential, or ErrCredentialNotFound.
+	CredentialByID(ctx context.Context, id string) (*Credential, error)
+	// UpdateSignCount stores the sign count reported by the last login.
+	UpdateSignCount(ctx context.Context, id string, signCount uint32) error
+}
+
+// Session is the state of a ceremony between its begin and finish requests.
+type Session struct {
+	User         webauthn.UserEntity                         // Registering user, or the user logging in (empty for discoverable logins)
+	Registration *webauthn.BeginRegistrationOptions          // Set for registrations
+	Login        *webauthn.PublicKeyCredentialRequestOptions // Set for logins
+	ExistingUser bool                                        // Set if the registration adds a credential to a signed-in user
+}
+
+// ChallengeStore keeps sessions between begin and finish, keyed by challenge.
+type ChallengeStore interface {
+	// Save stores the session until expiresAt.
+	Save(ctx context.Context, challenge string, session Session, expiresAt time.Time) error
+	// Take returns and deletes the session, so every challenge can be used once. Returns ErrChallengeNotFound
+	// for unknown and expired challenges.
+	Take(ctx context.Context, challenge string) (*Session, error)
+}
+
+// MemoryStore is an in-memory UserStore, CredentialStore and ChallengeStore for tests and demos.
+// Its data is lost on restart.
+type MemoryStore struct {
+	mu          sync.Mutex
+	users       map[string]webauthn.UserEntity // By name
+	credentials map[string]Credential          // By ID
+	sessions    map[string]memorySession       // By challenge
+}
+
+type memorySession struct {
+	session   Session
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       make(map[string]webauthn.UserEntity),
+		credentials: make(map[string]Credential),
+		sessions:    make(map[string]memorySession),
+	}
+}
+
+func (s *MemoryStore) UserByName(_ context.Context, name string) (*webauthn.UserEntity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[name]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (s *MemoryStore) UserByID(_ context.Context, id []byte) (*webauthn.UserEntity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if bytes.Equal(user.ID, id) {
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (s *MemoryStore) CreateUser(_ context.Context, user webauthn.UserEntity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[user.Name]; ok {
+		return ErrUserExists
+	}
+	s.users[user.Name] = user
+	return nil
+}
+
+func (s *MemoryStore) DeleteUser(_ context.Context, id []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, user := range s.users {
+		if bytes.Equal(user.ID, id) {
+			delete(s.users, name)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) AddCredential(_ context.Context, cred Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials[cred.ID] = cred
+	return nil
+}
+
+func (s *MemoryStore) CredentialsByUser(_ context.Context, userID []byte) ([]Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var creds []Credential
+	for _, cred := range s.credentials {
+		if bytes.Equal(cred.UserID, userID) {
+			creds = append(creds, cred)
+		}
+	}
+	return creds, nil
+}
+
+func (s *MemoryStore) CredentialByID(_ context.Context, id string) (*Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cred, ok := s.credentials[id]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	return &cred, nil
+}
+
+func (s *MemoryStore) UpdateSignCount(_ context.Context, id string, signCount uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cred, ok := s.credentials[id]
+	if !ok {
+		return ErrCredentialNotFound
+	}
+	cred.SignCount = signCount
+	s.credentials[id] = cred
+	return nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, challenge string, session Session, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Drop expired sessions, abandoned ceremonies would pile up otherwise
+	now := time.Now()
+	for c, stored := range s.sessions {
+		if now.After(stored.expiresAt) {
+			delete(s.sessions, c)
+		}
+	}
+	s.sessions[challenge] = memorySession{session: session, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *MemoryStore) Take(_ context.Context, challenge string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.sessions[challenge]
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+	delete(s.sessions, challenge)
+	if time.Now().After(stored.expiresAt) {
+		return nil, ErrChallengeNotFound
+	}
+	return &stored.session, nil
+}
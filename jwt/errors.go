@@ -0,0 +1,24 @@
+package jwt
+
+import "errors"
+
+var (
+	ErrMissingIssuer    = errors.New("issuer is required")
+	ErrNoKeys           = errors.New("at least one key is required")
+	ErrInvalidKeyID     = errors.New("key ID must not be empty")
+	ErrDuplicateKeyID   = errors.New("duplicate key ID")
+	ErrUnsupportedKey   = errors.New("unsupported key, use an ECDSA P-256 or Ed25519 key")
+	ErrNilLoginResult   = errors.New("login result cannot be nil")
+	ErrMissingUserID    = errors.New("login result has no user ID")
+	ErrFailedSign       = errors.New("failed to sign token")
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported token algorithm")
+	ErrUnknownKeyID     = errors.New("token signed with an unknown key")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrInvalidJWK       = errors.New("invalid JWK")
+	ErrIssuerMismatch   = errors.New("token issuer mismatch")
+	ErrAudienceMismatch = errors.New("token audience mismatch")
+	ErrExpired          = errors.New("token expired")
+	ErrNotYetValid      = errors.New("token not yet valid")
+	ErrMissingAudience  = errors.New("audience is required")
+)
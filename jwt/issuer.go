@@ -0,0 +1,187 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+)
+
+// defaultLifetime is the token lifetime if Config.Lifetime is not set.
+const defaultLifetime = 15 * time.Minute
+
+// Key is a signing key tagged with its ID.
+type Key struct {
+	ID string
+	// Signer is an ECDSA P-256 key (ES256) or an Ed25519 key (EdDSA), e.g., *ecdsa.PrivateKey,
+	// ed25519.PrivateKey or a key kept in a KMS.
+	Signer crypto.Signer
+}
+
+// Config holds the configuration of an Issuer.
+type Config struct {
+	Issuer   string        // "iss" claim, e.g., "https://login.example.com"
+	Audience []string      // "aud" claim, the services accepting the tokens
+	Lifetime time.Duration // Defaults to 15 minutes
+	// Keys are the signing keys. The first one signs new tokens, all of them are published by JWKS,
+	// so keys are rotated by putting a new one in front and dropping the old one once its tokens expired.
+	Keys []Key
+	// ACR derives the "acr" claim, defaults to DefaultACR. Optional.
+	ACR func(result *webauthn.LoginResult) string
+}
+
+// Issuer signs tokens.
+type Issuer struct {
+	config Config
+	algs   []string // JWS algorithm of every key
+}
+
+// New validates the configuration and creates an Issuer.
+func New(config Config) (*Issuer, error) {
+	if config.Issuer == "" {
+		return nil, ErrMissingIssuer
+	}
+	if len(config.Audience) == 0 {
+		return nil, ErrMissingAudience
+	}
+	if len(config.Keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	seen := make(map[string]bool, len(config.Keys))
+	algs := make([]string, len(config.Keys))
+	for i, key := range config.Keys {
+		if key.ID == "" {
+			return nil, ErrInvalidKeyID
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateKeyID, key.ID)
+		}
+		seen[key.ID] = true
+		alg, err := keyAlgorithm(key.Signer)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s", err, key.ID)
+		}
+		algs[i] = alg
+	}
+	if config.Lifetime <= 0 {
+		config.Lifetime = defaultLifetime
+	}
+	if config.ACR == nil {
+		config.ACR = DefaultACR
+	}
+	return &Issuer{config: config, algs: algs}, nil
+}
+
+// DefaultACR returns "phrh" for device-bound credentials and "phr" for backup eligible (synced) ones.
+// Both are multi-factor classes, so without user verification (the key alone) it returns ACRSingleFactor.
+func DefaultACR(result *webauthn.LoginResult) string {
+	if !result.UserVerified {
+		return ACRSingleFactor
+	}
+	if result.BackupEligible {
+		return ACRPhishingResistant
+	}
+	return ACRPhishingResistantHardware
+}
+
+// IssueLogin signs a token for a successful login. result.UserID must be set (FinishConditionalLogin and
+// the httpapi handler set it, after FinishLogin the caller does).
+func (i *Issuer) IssueLogin(result *webauthn.LoginResult) (string, *Claims, error) {
+	if result == nil {
+		return "", nil, ErrNilLoginResult
+	}
+	if len(result.UserID) == 0 {
+		return "", nil, ErrMissingUserID
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	claims := &Claims{
+		Issuer:    i.config.Issuer,
+		Subject:   base64.RawURLEncoding.EncodeToString(result.UserID),
+		Audience:  i.config.Audience,
+		ExpiresAt: now.Add(i.config.Lifetime).Unix(),
+		IssuedAt:  now.Unix(),
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		AuthTime:  now.Unix(),
+		AMR:       result.AMR(),
+		ACR:       i.ACR(result),
+	}
+	token, err := i.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// ACR returns the "acr" claim of the login, derived by Config.ACR.
+func (i *Issuer) ACR(result *webauthn.LoginResult) string {
+	return i.config.ACR(result)
+}
+
+// Sign signs arbitrary claims with the first key. The caller is responsible for their contents.
+func (i *Issuer) Sign(claims any) (string, error) {
+	key, alg := i.config.Keys[0], i.algs[0]
+	headerJSON, err := json.Marshal(header{Alg: alg, Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch alg {
+	case AlgES256:
+		digest := sha256.Sum256([]byte(signingInput))
+		der, err := key.Signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrFailedSign, err)
+		}
+		// JWS uses the fixed-size r || s form instead of ASN.1
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &sig); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrFailedSign, err)
+		}
+		signature = make([]byte, 64)
+		sig.R.FillBytes(signature[:32])
+		sig.S.FillBytes(signature[32:])
+	case AlgEdDSA:
+		if signature, err = key.Signer.Sign(rand.Reader, []byte(signingInput), crypto.Hash(0)); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrFailedSign, err)
+		}
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// keyAlgorithm returns the JWS algorithm of the signer's key.
+func keyAlgorithm(signer crypto.Signer) (string, error) {
+	if signer == nil {
+		return "", ErrUnsupportedKey
+	}
+	switch pub := signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return "", ErrUnsupportedKey
+		}
+		return AlgES256, nil
+	case ed25519.PublicKey:
+		return AlgEdDSA, nil
+	default:
+		return "", ErrUnsupportedKey
+	}
+}
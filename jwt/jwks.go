@@ -0,0 +1,73 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK is a public JSON Web Key (RFC 7517) of an EC P-256 or OKP Ed25519 key.
+type JWK struct {
+	Kty string `json:"kty"` // "EC" or "OKP"
+	Crv string `json:"crv"` // "P-256" or "Ed25519"
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"` // EC only
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, serve it as JSON, e.g., at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of all signing keys.
+func (i *Issuer) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(i.config.Keys))}
+	for _, key := range i.config.Keys {
+		jwk := JWK{Kid: key.ID, Use: "sig"}
+		switch pub := key.Signer.Public().(type) {
+		case *ecdsa.PublicKey:
+			jwk.Kty, jwk.Crv, jwk.Alg = "EC", "P-256", AlgES256
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, 32)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, 32)))
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv, jwk.Alg = "OKP", "Ed25519", AlgEdDSA
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// PublicKey decodes the key and returns its JWS algorithm.
+func (k JWK) PublicKey() (crypto.PublicKey, string, error) {
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s: %w", ErrInvalidJWK, k.Kid, err)
+	}
+	switch {
+	case k.Kty == "EC" && k.Crv == "P-256":
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %s: %w", ErrInvalidJWK, k.Kid, err)
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if len(x) != 32 || len(y) != 32 || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, "", fmt.Errorf("%w: %s: invalid point", ErrInvalidJWK, k.Kid)
+		}
+		return pub, AlgES256, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		if len(x) != ed25519.PublicKeySize {
+			return nil, "", fmt.Errorf("%w: %s: invalid key size", ErrInvalidJWK, k.Kid)
+		}
+		return ed25519.PublicKey(x), AlgEdDSA, nil
+	default:
+		return nil, "", fmt.Errorf("%w: %s: unsupported key type %s %s", ErrInvalidJWK, k.Kid, k.Kty, k.Crv)
+	}
+}
@@ -0,0 +1,399 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+)
+
+func b64(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", s, err)
+	}
+	return b
+}
+
+// rfc7515Key is the public ES256 key of RFC 7515, appendix A.3.
+func rfc7515Key(t *testing.T) *ecdsa.PublicKey {
+	t.Helper()
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(b64(t, "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU")),
+		Y:     new(big.Int).SetBytes(b64(t, "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0")),
+	}
+}
+
+// publicSigner publishes a known public key, for JWKS tests. It can't sign.
+type publicSigner struct {
+	pub crypto.PublicKey
+}
+
+func (s publicSigner) Public() crypto.PublicKey { return s.pub }
+
+func (s publicSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("public key only")
+}
+
+func esKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+// rfc8037Key is the Ed25519 key of RFC 8037, appendix A.1.
+func rfc8037Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	return ed25519.NewKeyFromSeed(b64(t, "nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A"))
+}
+
+func newTestIssuer(t *testing.T, config Config) *Issuer {
+	t.Helper()
+	if config.Issuer == "" {
+		config.Issuer = "https://login.example.com"
+	}
+	if config.Audience == nil {
+		config.Audience = []string{"https://api.example.com"}
+	}
+	if config.Keys == nil {
+		config.Keys = []Key{{ID: "es", Signer: esKey(t)}}
+	}
+	i, err := New(config)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return i
+}
+
+func newTestVerifier(t *testing.T, i *Issuer, config VerifierConfig) *Verifier {
+	t.Helper()
+	config.Keys = i.JWKS()
+	if config.Issuer == "" {
+		config.Issuer = "https://login.example.com"
+	}
+	if config.Audience == "" {
+		config.Audience = "https://api.example.com"
+	}
+	v, err := NewVerifier(config)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+func TestNew(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := Key{ID: "es", Signer: esKey(t)}
+	ed := Key{ID: "ed", Signer: rfc8037Key(t)}
+	tests := []struct {
+		name   string
+		config Config
+		err    error
+	}{
+		{"valid", Config{Issuer: "iss", Audience: []string{"aud"}, Keys: []Key{es, ed}}, nil},
+		{"missing issuer", Config{Audience: []string{"aud"}, Keys: []Key{es}}, ErrMissingIssuer},
+		{"missing audience", Config{Issuer: "iss", Keys: []Key{es}}, ErrMissingAudience},
+		{"no keys", Config{Issuer: "iss", Audience: []string{"aud"}}, ErrNoKeys},
+		{"empty key ID", Config{Issuer: "iss", Audience: []string{"aud"}, Keys: []Key{{Signer: es.Signer}}}, ErrInvalidKeyID},
+		{"duplicate key ID", Config{Issuer: "iss", Audience: []string{"aud"}, Keys: []Key{es, {ID: "es", Signer: ed.Signer}}}, ErrDuplicateKeyID},
+		{"nil signer", Config{Issuer: "iss", Audience: []string{"aud"}, Keys: []Key{{ID: "k"}}}, ErrUnsupportedKey},
+		{"P-384 key", Config{Issuer: "iss", Audience: []string{"aud"}, Keys: []Key{{ID: "k", Signer: p384}}}, ErrUnsupportedKey},
+		{"RSA key", Config{Issuer: "iss", Audience: []string{"aud"}, Keys: []Key{{ID: "k", Signer: rsaKey}}}, ErrUnsupportedKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); !errors.Is(err, tt.err) {
+				t.Fatalf("New() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// The vectors are the ES256 example of RFC 7515, appendix A.3, and the Ed25519 example of RFC 8037, appendix A.4.
+func TestVerifySignatureKnownAnswer(t *testing.T) {
+	tests := []struct {
+		name         string
+		key          crypto.PublicKey
+		alg          string
+		signingInput string
+		signature    string
+	}{
+		{
+			name:         "ES256",
+			key:          rfc7515Key(t),
+			alg:          AlgES256,
+			signingInput: "eyJhbGciOiJFUzI1NiJ9.eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ",
+			signature:    "DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q",
+		},
+		{
+			name:         "EdDSA",
+			key:          rfc8037Key(t).Public(),
+			alg:          AlgEdDSA,
+			signingInput: "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc",
+			signature:    "hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := verificationKey{pub: tt.key, alg: tt.alg}
+			signature := b64(t, tt.signature)
+			if !verifySignature(key, tt.signingInput, signature) {
+				t.Fatal("valid signature rejected")
+			}
+			if verifySignature(key, tt.signingInput+"x", signature) {
+				t.Error("signature over other data accepted")
+			}
+			signature[len(signature)-1] ^= 1
+			if verifySignature(key, tt.signingInput, signature) {
+				t.Error("modified signature accepted")
+			}
+			if verifySignature(key, tt.signingInput, signature[:len(signature)-1]) {
+				t.Error("truncated signature accepted")
+			}
+		})
+	}
+}
+
+// Ed25519 signatures are deterministic, so the token of the RFC 8037 key is fixed.
+func TestSignKnownAnswer(t *testing.T) {
+	i := newTestIssuer(t, Config{Keys: []Key{{ID: "ed", Signer: rfc8037Key(t)}}})
+	token, err := i.Sign(map[string]string{"sub": "AQID"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	// {"alg":"EdDSA","typ":"JWT","kid":"ed"}.{"sub":"AQID"}.signature
+	const want = "eyJhbGciOiJFZERTQSIsInR5cCI6IkpXVCIsImtpZCI6ImVkIn0.eyJzdWIiOiJBUUlEIn0." +
+		"NPCWDkJF09rHR2200OEeWamdrURJ_OVt8fI1_ibBIlMlsYldBo7CbrJaBlOv9ErWmDEhxWKy1HDloRK5UUxKCw"
+	if token != want {
+		t.Fatalf("Sign() = %s, want %s", token, want)
+	}
+}
+
+// The public keys are those given in RFC 7515, appendix A.3, and RFC 8037, appendix A.2.
+func TestJWKSKnownAnswer(t *testing.T) {
+	i := newTestIssuer(t, Config{Keys: []Key{{ID: "es", Signer: publicSigner{rfc7515Key(t)}}, {ID: "ed", Signer: rfc8037Key(t)}}})
+	want := JWKS{Keys: []JWK{
+		{Kty: "EC", Crv: "P-256", X: "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU", Y: "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0", Kid: "es", Use: "sig", Alg: AlgES256},
+		{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo", Kid: "ed", Use: "sig", Alg: AlgEdDSA},
+	}}
+	if got := i.JWKS(); !slices.Equal(got.Keys, want.Keys) {
+		t.Fatalf("JWKS() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIssueLoginVerify(t *testing.T) {
+	keys := map[string]crypto.Signer{AlgES256: esKey(t), AlgEdDSA: rfc8037Key(t)}
+	for alg, signer := range keys {
+		t.Run(alg, func(t *testing.T) {
+			i := newTestIssuer(t, Config{Keys: []Key{{ID: "k", Signer: signer}}, Lifetime: time.Hour})
+			v := newTestVerifier(t, i, VerifierConfig{})
+			result := &webauthn.LoginResult{UserID: []byte{1, 2, 3}, UserVerified: true}
+			token, issued, err := i.IssueLogin(result)
+			if err != nil {
+				t.Fatalf("IssueLogin: %v", err)
+			}
+			claims, err := v.Verify(token)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.Subject != "AQID" || claims.ID != issued.ID || claims.ExpiresAt-claims.IssuedAt != 3600 {
+				t.Errorf("Verify() claims = %+v, want %+v", claims, issued)
+			}
+			if claims.ACR != ACRPhishingResistantHardware {
+				t.Errorf("acr = %s, want %s", claims.ACR, ACRPhishingResistantHardware)
+			}
+			if want := []string{webauthn.AMRHardwareKey, webauthn.AMRUser}; !slices.Equal(claims.AMR, want) {
+				t.Errorf("amr = %v, want %v", claims.AMR, want)
+			}
+		})
+	}
+}
+
+func TestACR(t *testing.T) {
+	tests := []struct {
+		name   string
+		acr    func(*webauthn.LoginResult) string
+		result *webauthn.LoginResult
+		want   string
+	}{
+		{"device-bound", nil, &webauthn.LoginResult{UserID: []byte{1}, UserVerified: true}, ACRPhishingResistantHardware},
+		{"synced", nil, &webauthn.LoginResult{UserID: []byte{1}, UserVerified: true, BackupEligible: true}, ACRPhishingResistant},
+		{"device-bound without UV", nil, &webauthn.LoginResult{UserID: []byte{1}}, ACRSingleFactor},
+		{"synced without UV", nil, &webauthn.LoginResult{UserID: []byte{1}, BackupEligible: true}, ACRSingleFactor},
+		{"custom", func(*webauthn.LoginResult) string { return "urn:example:gold" }, &webauthn.LoginResult{UserID: []byte{1}}, "urn:example:gold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := newTestIssuer(t, Config{ACR: tt.acr})
+			if got := i.ACR(tt.result); got != tt.want {
+				t.Errorf("ACR() = %s, want %s", got, tt.want)
+			}
+			_, claims, err := i.IssueLogin(tt.result)
+			if err != nil {
+				t.Fatalf("IssueLogin: %v", err)
+			}
+			if claims.ACR != tt.want {
+				t.Errorf("acr claim = %s, want %s", claims.ACR, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssueLoginErrors(t *testing.T) {
+	i := newTestIssuer(t, Config{})
+	if _, _, err := i.IssueLogin(nil); !errors.Is(err, ErrNilLoginResult) {
+		t.Errorf("IssueLogin(nil) error = %v, want %v", err, ErrNilLoginResult)
+	}
+	if _, _, err := i.IssueLogin(&webauthn.LoginResult{}); !errors.Is(err, ErrMissingUserID) {
+		t.Errorf("IssueLogin() without user ID error = %v, want %v", err, ErrMissingUserID)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	i := newTestIssuer(t, Config{})
+	v := newTestVerifier(t, i, VerifierConfig{Leeway: time.Minute})
+	now := time.Now()
+	sign := func(claims Claims) string {
+		t.Helper()
+		token, err := i.Sign(claims)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return token
+	}
+	valid := Claims{Issuer: "https://login.example.com", Audience: Audience{"https://api.example.com"}, ExpiresAt: now.Add(time.Hour).Unix()}
+	with := func(change func(c *Claims)) string {
+		c := valid
+		change(&c)
+		return sign(c)
+	}
+	token := sign(valid)
+	parts := strings.Split(token, ".")
+	encode := func(v any) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	other := newTestIssuer(t, Config{Keys: []Key{{ID: "es", Signer: rfc8037Key(t)}}})
+	edToken, err := other.Sign(valid)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"valid", token, nil},
+		{"multiple audiences", with(func(c *Claims) { c.Audience = Audience{"other", "https://api.example.com"} }), nil},
+		{"expired within leeway", with(func(c *Claims) { c.ExpiresAt = now.Add(-30 * time.Second).Unix() }), nil},
+		{"malformed", "a.b", ErrMalformedToken},
+		{"header not JSON", "bm90IGpzb24." + parts[1] + "." + parts[2], ErrMalformedToken},
+		{"unknown key ID", encode(header{Alg: AlgES256, Kid: "other"}) + "." + parts[1] + "." + parts[2], ErrUnknownKeyID},
+		{"alg none", encode(header{Alg: "none", Kid: "es"}) + "." + parts[1] + ".", ErrUnsupportedAlg},
+		{"alg HS256", encode(header{Alg: "HS256", Kid: "es"}) + "." + parts[1] + "." + parts[2], ErrUnsupportedAlg},
+		{"alg of another key", edToken, ErrUnsupportedAlg},
+		{"payload not base64url", parts[0] + ".!!." + parts[2], ErrMalformedToken},
+		{"claims changed", parts[0] + "." + encode(Claims{Issuer: "https://login.example.com", Audience: Audience{"https://api.example.com"}, ExpiresAt: now.Add(48 * time.Hour).Unix()}) + "." + parts[2], ErrInvalidSignature},
+		{"wrong issuer", with(func(c *Claims) { c.Issuer = "https://evil.example.com" }), ErrIssuerMismatch},
+		{"wrong audience", with(func(c *Claims) { c.Audience = Audience{"https://other.example.com"} }), ErrAudienceMismatch},
+		{"expired", with(func(c *Claims) { c.ExpiresAt = now.Add(-2 * time.Minute).Unix() }), ErrExpired},
+		{"no expiry", with(func(c *Claims) { c.ExpiresAt = 0 }), ErrExpired},
+		{"not yet valid", with(func(c *Claims) { c.NotBefore = now.Add(2 * time.Minute).Unix() }), ErrNotYetValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(tt.token); !errors.Is(err, tt.err) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	jwks := newTestIssuer(t, Config{}).JWKS()
+	valid := jwks.Keys[0]
+	offCurve := valid
+	offCurve.Y = offCurve.X
+	wrongAlg := valid
+	wrongAlg.Alg = AlgEdDSA
+	shortEd := JWK{Kty: "OKP", Crv: "Ed25519", X: "AAAA", Kid: "ed"}
+	rsaJWK := JWK{Kty: "RSA", Kid: "rsa", X: "AQAB"}
+	noKid := valid
+	noKid.Kid = ""
+	tests := []struct {
+		name string
+		keys []JWK
+		err  error
+	}{
+		{"valid", []JWK{valid}, nil},
+		{"no keys", nil, ErrNoKeys},
+		{"missing key ID", []JWK{noKid}, ErrInvalidKeyID},
+		{"duplicate key ID", []JWK{valid, valid}, ErrDuplicateKeyID},
+		{"point not on curve", []JWK{offCurve}, ErrInvalidJWK},
+		{"alg doesn't match key", []JWK{wrongAlg}, ErrInvalidJWK},
+		{"short Ed25519 key", []JWK{shortEd}, ErrInvalidJWK},
+		{"unsupported key type", []JWK{rsaJWK}, ErrInvalidJWK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(VerifierConfig{Keys: JWKS{Keys: tt.keys}, Issuer: "iss", Audience: "aud"})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("NewVerifier() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAudienceJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		aud  Audience
+	}{
+		{`"a"`, Audience{"a"}},
+		{`["a","b"]`, Audience{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			var aud Audience
+			if err := json.Unmarshal([]byte(tt.json), &aud); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !slices.Equal(aud, tt.aud) {
+				t.Errorf("Unmarshal() = %v, want %v", aud, tt.aud)
+			}
+			b, err := json.Marshal(aud)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(b) != tt.json {
+				t.Errorf("Marshal() = %s, want %s", b, tt.json)
+			}
+		})
+	}
+}
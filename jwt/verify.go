@@ -0,0 +1,130 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// VerifierConfig holds the configuration of a Verifier.
+type VerifierConfig struct {
+	Keys     JWKS          // Keys published by the issuer
+	Issuer   string        // Expected "iss" claim
+	Audience string        // This service, must be in the "aud" claim
+	Leeway   time.Duration // Allowed clock skew for "exp" and "nbf". Optional.
+}
+
+// Verifier validates tokens locally against a JWKS.
+type Verifier struct {
+	config VerifierConfig
+	keys   map[string]verificationKey
+}
+
+type verificationKey struct {
+	pub crypto.PublicKey
+	alg string
+}
+
+// NewVerifier decodes the keys and creates a Verifier.
+func NewVerifier(config VerifierConfig) (*Verifier, error) {
+	if config.Issuer == "" {
+		return nil, ErrMissingIssuer
+	}
+	if config.Audience == "" {
+		return nil, ErrMissingAudience
+	}
+	if len(config.Keys.Keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	keys := make(map[string]verificationKey, len(config.Keys.Keys))
+	for _, jwk := range config.Keys.Keys {
+		if jwk.Kid == "" {
+			return nil, ErrInvalidKeyID
+		}
+		if _, ok := keys[jwk.Kid]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateKeyID, jwk.Kid)
+		}
+		pub, alg, err := jwk.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if jwk.Alg != "" && jwk.Alg != alg {
+			return nil, fmt.Errorf("%w: %s: alg %s doesn't match the key", ErrInvalidJWK, jwk.Kid, jwk.Alg)
+		}
+		keys[jwk.Kid] = verificationKey{pub: pub, alg: alg}
+	}
+	return &Verifier{config: config, keys: keys}, nil
+}
+
+// Verify checks the signature, issuer, audience and validity period of the token and returns its claims.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	var claims Claims
+	if err := v.VerifyInto(token, &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// VerifyInto is like Verify, but also decodes the payload into claims, which may embed Claims
+// to read additional members.
+func (v *Verifier) VerifyInto(token string, claims any) error {
+	h, signingInput, payload, signature, err := splitToken(token)
+	if err != nil {
+		return err
+	}
+	key, ok := v.keys[h.Kid]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyID, h.Kid)
+	}
+	// The algorithm is taken from the key, never from the token alone
+	if h.Alg != key.alg {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlg, h.Alg)
+	}
+	if !verifySignature(key, signingInput, signature) {
+		return ErrInvalidSignature
+	}
+
+	var std Claims
+	if err := json.Unmarshal(payload, &std); err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+	if std.Issuer != v.config.Issuer {
+		return fmt.Errorf("%w: %s", ErrIssuerMismatch, std.Issuer)
+	}
+	if !std.Audience.Contains(v.config.Audience) {
+		return ErrAudienceMismatch
+	}
+	now := time.Now()
+	if !now.Before(time.Unix(std.ExpiresAt, 0).Add(v.config.Leeway)) {
+		return ErrExpired
+	}
+	if std.NotBefore != 0 && now.Add(v.config.Leeway).Before(time.Unix(std.NotBefore, 0)) {
+		return ErrNotYetValid
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+	return nil
+}
+
+// verifySignature checks a JWS signature with the key.
+func verifySignature(key verificationKey, signingInput string, signature []byte) bool {
+	switch pub := key.pub.(type) {
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, []byte(signingInput), signature)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,131 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LargeBlobSupport is the registration preference of the largeBlob extension.
+type LargeBlobSupport string
+
+const (
+	LargeBlobRequired  LargeBlobSupport = "required"
+	LargeBlobPreferred LargeBlobSupport = "preferred"
+)
+
+// IsValid checks if the LargeBlobSupport is one of the defined constants.
+func (s LargeBlobSupport) IsValid() bool {
+	switch s {
+	case LargeBlobRequired, LargeBlobPreferred:
+		return true
+	default:
+		return false
+	}
+}
+
+// LargeBlobInputs is the client input of the largeBlob extension.
+type LargeBlobInputs struct {
+	Support LargeBlobSupport `json:"support,omitempty"` // Registration only
+	Read    bool             `json:"read,omitempty"`    // Login only
+	Write   Base64URL        `json:"write,omitempty"`   // Login only, mutually exclusive with Read
+}
+
+// LargeBlobOutputs is the client output of the largeBlob extension.
+type LargeBlobOutputs struct {
+	Supported *bool     `json:"supported,omitempty"` // Registration only, whether the credential can store a blob
+	Blob      Base64URL `json:"blob,omitempty"`      // Login only, the blob read from the authenticator
+	Written   *bool     `json:"written,omitempty"`   // Login only, whether the write succeeded
+}
+
+const largeBlobIdentifier = "largeBlob"
+
+// LargeBlobExtension stores an opaque blob with the credential on the authenticator,
+// see WithLargeBlobSupport, WithLargeBlobRead and WithLargeBlobWrite.
+type LargeBlobExtension struct {
+	Inputs LargeBlobInputs
+}
+
+// WithLargeBlobSupport requests a credential able to store a large blob. Registration only.
+func WithLargeBlobSupport(support LargeBlobSupport) Option {
+	return WithExtension(&LargeBlobExtension{Inputs: LargeBlobInputs{Support: support}})
+}
+
+// WithLargeBlobRead asks the authenticator for the blob stored with the credential. Login only.
+func WithLargeBlobRead() Option {
+	return WithExtension(&LargeBlobExtension{Inputs: LargeBlobInputs{Read: true}})
+}
+
+// WithLargeBlobWrite stores the blob with the credential, replacing the previous one. Login only,
+// and the allow list must contain exactly one credential.
+func WithLargeBlobWrite(blob []byte) Option {
+	return WithExtension(&LargeBlobExtension{Inputs: LargeBlobInputs{Write: blob}})
+}
+
+// Identifier returns "largeBlob".
+func (e *LargeBlobExtension) Identifier() string { return largeBlobIdentifier }
+
+// AddInput checks the inputs against the ceremony and adds them.
+func (e *LargeBlobExtension) AddInput(c *CeremonyOptions) error {
+	inputs := e.Inputs
+	switch c.Ceremony {
+	case CeremonyRegistration:
+		if inputs.Read || inputs.Write != nil {
+			return fmt.Errorf("%w: largeBlob read and write", ErrOptionNotApplicable)
+		}
+		if !inputs.Support.IsValid() {
+			return fmt.Errorf("%w: support %q", ErrInvalidLargeBlobInputs, inputs.Support)
+		}
+	case CeremonyAuthentication:
+		if inputs.Support != "" {
+			return fmt.Errorf("%w: largeBlob support", ErrOptionNotApplicable)
+		}
+		if inputs.Read == (inputs.Write != nil) {
+			return fmt.Errorf("%w: exactly one of read and write is needed", ErrInvalidLargeBlobInputs)
+		}
+		if inputs.Write != nil {
+			if len(inputs.Write) == 0 {
+				return fmt.Errorf("%w: blob is empty", ErrInvalidLargeBlobInputs)
+			}
+			if len(c.Login.AllowCredentials) != 1 {
+				return fmt.Errorf("%w: write needs exactly one allowed credential, got %d", ErrInvalidLargeBlobInputs, len(c.Login.AllowCredentials))
+			}
+		}
+	}
+	return c.SetExtensionInput(largeBlobIdentifier, inputs)
+}
+
+// ValidateAuthenticatorOutput does nothing, the client reads and writes the blob itself.
+func (e *LargeBlobExtension) ValidateAuthenticatorOutput(Ceremony, *AuthenticatorExtensionOutputs, *Extensions) error {
+	return nil
+}
+
+// ValidateClientOutput checks that only the outputs matching the requested operation are reported.
+func (e *LargeBlobExtension) ValidateClientOutput(ceremony Ceremony, output json.RawMessage, result *Extensions) error {
+	if output == nil {
+		return nil
+	}
+	var out LargeBlobOutputs
+	if err := decodeExtensionOutput(largeBlobIdentifier, output, &out); err != nil {
+		return err
+	}
+	switch {
+	case ceremony == CeremonyRegistration && (out.Blob != nil || out.Written != nil):
+		return fmt.Errorf("%w: only supported is reported at registration", ErrInvalidLargeBlobOutputs)
+	case ceremony == CeremonyAuthentication && out.Supported != nil:
+		return fmt.Errorf("%w: supported is only reported at registration", ErrInvalidLargeBlobOutputs)
+	case out.Blob != nil && !e.Inputs.Read:
+		return fmt.Errorf("%w: blob without read", ErrInvalidLargeBlobOutputs)
+	case out.Written != nil && e.Inputs.Write == nil:
+		return fmt.Errorf("%w: written without write", ErrInvalidLargeBlobOutputs)
+	}
+	result.LargeBlob = &out
+	return nil
+}
+
+func decodeLargeBlobExtension(_ Ceremony, inputs ExtensionInputs) (Extension, error) {
+	ext := &LargeBlobExtension{}
+	if ok, err := decodeExtensionInput(inputs, largeBlobIdentifier, &ext.Inputs); !ok {
+		return nil, err
+	}
+	return ext, nil
+}
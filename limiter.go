@@ -0,0 +1,251 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultMaxLimiterKeys bounds the keys tracked by a MemoryLimiter.
+const defaultMaxLimiterKeys = 100_000
+
+// LimitKind is the kind of subject a login attempt is counted against.
+type LimitKind string
+
+const (
+	LimitUser       LimitKind = "user"
+	LimitCredential LimitKind = "credential"
+	LimitIP         LimitKind = "ip"
+)
+
+// LimitKey identifies one subject of a login attempt, e.g., {LimitIP, "203.0.113.7"}.
+type LimitKey struct {
+	Kind  LimitKind
+	Value string
+}
+
+// Limiter protects assertion verification against brute force. Set it in Config.Limiter.
+// Every ceremony validating an assertion checks the keys of the attempt first and reports the outcome afterwards.
+// Implement it on top of a shared backend (e.g., Redis) if you run several instances of your server.
+type Limiter interface {
+	// Check returns a *LockoutError if any of the keys is locked out.
+	Check(keys []LimitKey) error
+	// Failure counts a failed attempt against every key, locking out those over their limit.
+	Failure(keys []LimitKey) error
+	// Success resets the failures of the keys.
+	Success(keys []LimitKey) error
+}
+
+// LockoutError is returned while a key is locked out. It wraps ErrRateLimited.
+type LockoutError struct {
+	Key   LimitKey
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s: %s locked out until %s", ErrRateLimited, e.Key.Kind, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// Limit allows Max failures within Window, and locks the key out for Lockout after that.
+type Limit struct {
+	Max     int
+	Window  time.Duration
+	Lockout time.Duration
+}
+
+// DefaultLimits are used by NewMemoryLimiter for kinds without a limit. Clients behind a shared IP (e.g., an office)
+// fail together, so IPs get more room.
+var DefaultLimits = map[LimitKind]Limit{
+	LimitUser:       {Max: 5, Window: 15 * time.Minute, Lockout: 15 * time.Minute},
+	LimitCredential: {Max: 5, Window: 15 * time.Minute, Lockout: 15 * time.Minute},
+	LimitIP:         {Max: 30, Window: 15 * time.Minute, Lockout: 15 * time.Minute},
+}
+
+// MemoryLimiter is an in-memory Limiter counting failures in a sliding window per key.
+// It tracks a bounded number of keys, so floods of random keys can't grow it without bound.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	limits  map[LimitKind]Limit
+	maxKeys int
+	keys    map[LimitKey]*limiterEntry
+	now     func() time.Time // time.Now, replaced in tests
+}
+
+type limiterEntry struct {
+	failures    []time.Time // Failures within the window, oldest first
+	lockedUntil time.Time
+}
+
+// NewMemoryLimiter creates a MemoryLimiter. Kinds missing from limits use DefaultLimits.
+func NewMemoryLimiter(limits map[LimitKind]Limit) *MemoryLimiter {
+	merged := make(map[LimitKind]Limit, len(DefaultLimits))
+	for kind, limit := range DefaultLimits {
+		merged[kind] = limit
+	}
+	for kind, limit := range limits {
+		merged[kind] = limit
+	}
+	return &MemoryLimiter{
+		limits:  merged,
+		maxKeys: defaultMaxLimiterKeys,
+		keys:    make(map[LimitKey]*limiterEntry),
+		now:     time.Now,
+	}
+}
+
+// Check returns a *LockoutError for the first locked out key.
+func (l *MemoryLimiter) Check(keys []LimitKey) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for _, key := range keys {
+		if entry, ok := l.keys[key]; ok && now.Before(entry.lockedUntil) {
+			return &LockoutError{Key: key, Until: entry.lockedUntil}
+		}
+	}
+	return nil
+}
+
+// Failure records a failure for every key with a limit.
+func (l *MemoryLimiter) Failure(keys []LimitKey) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for _, key := range keys {
+		limit, ok := l.limits[key.Kind]
+		if !ok || limit.Max <= 0 {
+			continue
+		}
+		entry, ok := l.keys[key]
+		if !ok {
+			l.makeRoom(now)
+			entry = &limiterEntry{}
+			l.keys[key] = entry
+		}
+		entry.failures = append(pruneFailures(entry.failures, now.Add(-limit.Window)), now)
+		if len(entry.failures) >= limit.Max {
+			entry.lockedUntil = now.Add(limit.Lockout)
+			entry.failures = nil
+		}
+	}
+	return nil
+}
+
+// Success forgets the keys.
+func (l *MemoryLimiter) Success(keys []LimitKey) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		delete(l.keys, key)
+	}
+	return nil
+}
+
+// makeRoom drops stale keys when the limiter is full, and an arbitrary key if none is stale.
+func (l *MemoryLimiter) makeRoom(now time.Time) {
+	if len(l.keys) < l.maxKeys {
+		return
+	}
+	for key, entry := range l.keys {
+		limit := l.limits[key.Kind]
+		if now.After(entry.lockedUntil) && len(pruneFailures(entry.failures, now.Add(-limit.Window))) == 0 {
+			delete(l.keys, key)
+		}
+	}
+	for key := range l.keys {
+		if len(l.keys) < l.maxKeys {
+			break
+		}
+		delete(l.keys, key)
+	}
+}
+
+// pruneFailures drops the failures before since.
+func pruneFailures(failures []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(failures) && failures[i].Before(since) {
+		i++
+	}
+	return failures[i:]
+}
+
+// limitKeys returns the keys the attempt is counted against, skipping unknown ones.
+// The user handle isn't used, it's unverified input that would let anyone lock out any user.
+func (c *LoginData) limitKeys() []LimitKey {
+	var keys []LimitKey
+	if len(c.UserID) > 0 {
+		keys = append(keys, LimitKey{Kind: LimitUser, Value: base64.RawURLEncoding.EncodeToString(c.UserID)})
+	}
+	if c.CredentialID != "" {
+		keys = append(keys, LimitKey{Kind: LimitCredential, Value: c.CredentialID})
+	}
+	if c.ClientIP != "" {
+		keys = append(keys, LimitKey{Kind: LimitIP, Value: c.ClientIP})
+	}
+	return keys
+}
+
+// ipLimitKeys returns the LimitIP keys of keys.
+func ipLimitKeys(keys []LimitKey) []LimitKey {
+	var ips []LimitKey
+	for _, key := range keys {
+		if key.Kind == LimitIP {
+			ips = append(ips, key)
+		}
+	}
+	return ips
+}
+
+// CheckLimits returns a *LockoutError if any of the keys is locked out by Config.Limiter, e.g., before
+// generating login options for a user. It always succeeds without a limiter.
+func (w *WebAuthn) CheckLimits(keys ...LimitKey) error {
+	if w == nil {
+		return ErrNilInstance
+	}
+	return w.checkLimiter(keys)
+}
+
+// checkLimiter checks the keys against Config.Limiter, if any.
+func (w *WebAuthn) checkLimiter(keys []LimitKey) error {
+	if w.Config.Limiter == nil || len(keys) == 0 {
+		return nil
+	}
+	err := w.Config.Limiter.Check(keys)
+	var lockout *LockoutError
+	if err == nil || errors.As(err, &lockout) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrLimiterFailed, err)
+}
+
+// recordLimiter reports the outcome of an attempt to Config.Limiter, if any.
+func (w *WebAuthn) recordLimiter(keys []LimitKey, outcome error) error {
+	if w.Config.Limiter == nil || len(keys) == 0 {
+		return nil
+	}
+	var err error
+	if outcome != nil {
+		err = w.Config.Limiter.Failure(keys)
+	} else {
+		// A success must not reset the IP, or an attacker could interleave logins to their own account with guesses
+		var reset []LimitKey
+		for _, key := range keys {
+			if key.Kind != LimitIP {
+				reset = append(reset, key)
+			}
+		}
+		if len(reset) > 0 {
+			err = w.Config.Limiter.Success(reset)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLimiterFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// testClock is a manual clock for the MemoryLimiter.
+type testClock struct {
+	t time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{t: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func userKey(value string) LimitKey       { return LimitKey{Kind: LimitUser, Value: value} }
+func credentialKey(value string) LimitKey { return LimitKey{Kind: LimitCredential, Value: value} }
+func ipKey(value string) LimitKey         { return LimitKey{Kind: LimitIP, Value: value} }
+
+func fail(l *MemoryLimiter, keys ...LimitKey) {
+	_ = l.Failure(keys)
+}
+
+func check(l *MemoryLimiter, keys ...LimitKey) error {
+	return l.Check(keys)
+}
+
+func newTestLimiter(clock *testClock) *MemoryLimiter {
+	l := NewMemoryLimiter(map[LimitKind]Limit{
+		LimitUser: {Max: 3, Window: 10 * time.Minute, Lockout: 15 * time.Minute},
+	})
+	l.now = clock.now
+	return l
+}
+
+func TestMemoryLimiterThreshold(t *testing.T) {
+	clock := newTestClock()
+	l := newTestLimiter(clock)
+	alice, bob := userKey("alice"), userKey("bob")
+
+	fail(l, alice)
+	fail(l, alice)
+	if err := check(l, alice); err != nil {
+		t.Fatalf("Check() after 2 of 3 failures error = %v, want nil", err)
+	}
+	fail(l, alice)
+	err := check(l, bob, alice)
+	var lockout *LockoutError
+	if !errors.As(err, &lockout) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Check() after 3 failures error = %v, want a *LockoutError", err)
+	}
+	if lockout.Key != alice || !lockout.Until.Equal(clock.t.Add(15*time.Minute)) {
+		t.Errorf("lockout = %+v, want alice until %s", lockout, clock.t.Add(15*time.Minute))
+	}
+	if err := check(l, bob); err != nil {
+		t.Errorf("Check() of another key error = %v, want nil", err)
+	}
+}
+
+func TestMemoryLimiterWindow(t *testing.T) {
+	clock := newTestClock()
+	l := newTestLimiter(clock)
+	alice := userKey("alice")
+
+	fail(l, alice)
+	clock.advance(6 * time.Minute)
+	fail(l, alice)
+	// The first failure leaves the window
+	clock.advance(5 * time.Minute)
+	fail(l, alice)
+	if err := check(l, alice); err != nil {
+		t.Fatalf("Check() with 2 failures in the window error = %v, want nil", err)
+	}
+	fail(l, alice)
+	if err := check(l, alice); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Check() with 3 failures in the window error = %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestMemoryLimiterLockoutDuration(t *testing.T) {
+	clock := newTestClock()
+	l := newTestLimiter(clock)
+	alice := userKey("alice")
+	for range 3 {
+		fail(l, alice)
+	}
+
+	clock.advance(15*time.Minute - time.Second)
+	if err := check(l, alice); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Check() before the lockout ends error = %v, want %v", err, ErrRateLimited)
+	}
+	clock.advance(2 * time.Second)
+	if err := check(l, alice); err != nil {
+		t.Fatalf("Check() after the lockout error = %v, want nil", err)
+	}
+	// The lockout starts a new count
+	fail(l, alice)
+	if err := check(l, alice); err != nil {
+		t.Fatalf("Check() after the first failure past the lockout error = %v, want nil", err)
+	}
+}
+
+func TestMemoryLimiterSuccess(t *testing.T) {
+	clock := newTestClock()
+	l := newTestLimiter(clock)
+	alice := userKey("alice")
+
+	fail(l, alice)
+	fail(l, alice)
+	if err := l.Success([]LimitKey{alice}); err != nil {
+		t.Fatalf("Success: %v", err)
+	}
+	fail(l, alice)
+	fail(l, alice)
+	if err := check(l, alice); err != nil {
+		t.Fatalf("Check() after a success error = %v, want nil", err)
+	}
+
+	fail(l, alice)
+	if err := l.Success([]LimitKey{alice}); err != nil {
+		t.Fatalf("Success: %v", err)
+	}
+	if err := check(l, alice); err != nil {
+		t.Fatalf("Check() of a locked out key after a success error = %v, want nil", err)
+	}
+}
+
+func TestMemoryLimiterDefaults(t *testing.T) {
+	clock := newTestClock()
+	l := NewMemoryLimiter(map[LimitKind]Limit{LimitCredential: {}})
+	l.now = clock.now
+
+	for range DefaultLimits[LimitIP].Max - 1 {
+		fail(l, ipKey("203.0.113.7"))
+	}
+	if err := check(l, ipKey("203.0.113.7")); err != nil {
+		t.Fatalf("Check() below the default IP limit error = %v, want nil", err)
+	}
+	fail(l, ipKey("203.0.113.7"))
+	if err := check(l, ipKey("203.0.113.7")); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Check() at the default IP limit error = %v, want %v", err, ErrRateLimited)
+	}
+	// A zero limit disables the kind
+	for range 100 {
+		fail(l, credentialKey("BAUG"))
+	}
+	if err := check(l, credentialKey("BAUG")); err != nil {
+		t.Fatalf("Check() of a disabled kind error = %v, want nil", err)
+	}
+}
+
+func TestMemoryLimiterMaxKeys(t *testing.T) {
+	clock := newTestClock()
+	l := newTestLimiter(clock)
+	l.maxKeys = 2
+	for _, name := range []string{"a", "b", "c", "d"} {
+		fail(l, userKey(name))
+	}
+	if len(l.keys) > 2 {
+		t.Fatalf("limiter tracks %d keys, want at most 2", len(l.keys))
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	var w *WebAuthn
+	if err := w.CheckLimits(ipKey("203.0.113.7")); !errors.Is(err, ErrNilInstance) {
+		t.Fatalf("CheckLimits() on nil error = %v, want %v", err, ErrNilInstance)
+	}
+	w = &WebAuthn{Config: &Config{}}
+	if err := w.CheckLimits(ipKey("203.0.113.7")); err != nil {
+		t.Fatalf("CheckLimits() without limiter error = %v, want nil", err)
+	}
+
+	clock := newTestClock()
+	l := newTestLimiter(clock)
+	w.Config.Limiter = l
+	for range 3 {
+		fail(l, userKey("alice"))
+	}
+	var lockout *LockoutError
+	if err := w.CheckLimits(ipKey("203.0.113.7"), userKey("alice")); !errors.As(err, &lockout) || lockout.Key != userKey("alice") {
+		t.Fatalf("CheckLimits() error = %v, want a lockout of alice", err)
+	}
+}
+
+func newLimitedWebAuthn(t *testing.T, limiter Limiter) *WebAuthn {
+	t.Helper()
+	w, err := New(&Config{
+		RPID:             "example.com",
+		RPDisplayName:    "Example",
+		RPOrigins:        []string{"https://example.com"},
+		Timeout:          60000,
+		UserVerification: UVPreferred,
+		Attestation:      AttestationNone,
+		Limiter:          limiter,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return w
+}
+
+// assertionKey signs test assertions for the RP ID example.com.
+type assertionKey struct {
+	key *ecdsa.PrivateKey
+}
+
+func newAssertionKey(t *testing.T) *assertionKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return &assertionKey{key: key}
+}
+
+// loginData returns a valid assertion of the challenge with sign count 1, by the credential BAUG of user AQID.
+func (k *assertionKey) loginData(t *testing.T, challenge string) *LoginData {
+	t.Helper()
+	publicKey, err := cbor.Marshal(map[int]any{
+		1: 2, 3: -7, -1: 1,
+		-2: k.key.X.FillBytes(make([]byte, 32)),
+		-3: k.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	rpIDHash := sha256.Sum256([]byte("example.com"))
+	authData := binary.BigEndian.AppendUint32(append(rpIDHash[:], 0x05), 1)
+	clientData, err := json.Marshal(map[string]string{"type": "webauthn.get", "challenge": challenge, "origin": "https://example.com"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, k.key, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1: %v", err)
+	}
+	return &LoginData{
+		ClientDataJSON: base64.RawURLEncoding.EncodeToString(clientData),
+		AuthData:       base64.RawURLEncoding.EncodeToString(authData),
+		Signature:      base64.RawURLEncoding.EncodeToString(signature),
+		PublicKey:      publicKey,
+		Options:        &PublicKeyCredentialRequestOptions{Challenge: challenge},
+		CredentialID:   "BAUG",
+		UserID:         []byte{1, 2, 3},
+		ClientIP:       "203.0.113.7",
+	}
+}
+
+// Malformed assertions only count against the client, failed checks of a resolved credential against all keys.
+func TestValidateAssertionLimits(t *testing.T) {
+	key := newAssertionKey(t)
+	other := newAssertionKey(t)
+	tests := []struct {
+		name   string
+		modify func(data *LoginData)
+		err    error
+		keys   []LimitKey // Keys that got a failure
+	}{
+		{"malformed client data", func(data *LoginData) { data.ClientDataJSON = "!" }, ErrFailedUnmarshalClientData, []LimitKey{ipKey("203.0.113.7")}},
+		{"malformed signature", func(data *LoginData) { data.Signature = "!" }, ErrFailedDecodeSignature, []LimitKey{ipKey("203.0.113.7")}},
+		{"truncated authenticator data", func(data *LoginData) { data.AuthData = data.AuthData[:20] }, ErrFailedParseClientData, []LimitKey{ipKey("203.0.113.7")}},
+		{"wrong challenge", func(data *LoginData) { data.Options.Challenge = "other" }, ErrChallengeMismatch,
+			[]LimitKey{userKey("AQID"), credentialKey("BAUG"), ipKey("203.0.113.7")}},
+		{"signed by another key", func(data *LoginData) { data.Signature = other.loginData(t, "c").Signature }, ErrInvalidSignature,
+			[]LimitKey{userKey("AQID"), credentialKey("BAUG"), ipKey("203.0.113.7")}},
+		{"sign count replay", func(data *LoginData) { data.StoredSignCount = 5 }, ErrSignatureCountMismatch,
+			[]LimitKey{userKey("AQID"), credentialKey("BAUG"), ipKey("203.0.113.7")}},
+		// The user handle is never a key
+		{"user handle only", func(data *LoginData) { data.UserID = nil; data.UserHandle = "AQID"; data.Options.Challenge = "other" }, ErrChallengeMismatch,
+			[]LimitKey{credentialKey("BAUG"), ipKey("203.0.113.7")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newTestClock()
+			l := NewMemoryLimiter(map[LimitKind]Limit{
+				LimitUser:       {Max: 1, Window: time.Minute, Lockout: time.Minute},
+				LimitCredential: {Max: 1, Window: time.Minute, Lockout: time.Minute},
+				LimitIP:         {Max: 1, Window: time.Minute, Lockout: time.Minute},
+			})
+			l.now = clock.now
+			w := newLimitedWebAuthn(t, l)
+
+			data := key.loginData(t, "c")
+			tt.modify(data)
+			if _, err := w.ValidateLoginData(data); !errors.Is(err, tt.err) {
+				t.Fatalf("ValidateLoginData() error = %v, want %v", err, tt.err)
+			}
+			for _, k := range []LimitKey{userKey("AQID"), credentialKey("BAUG"), ipKey("203.0.113.7")} {
+				locked := check(l, k) != nil
+				want := false
+				for _, counted := range tt.keys {
+					want = want || counted == k
+				}
+				if locked != want {
+					t.Errorf("%s locked = %t, want %t", k.Kind, locked, want)
+				}
+			}
+		})
+	}
+}
+
+// A success resets the user and credential, but never the IP.
+func TestValidateAssertionSuccessKeepsIP(t *testing.T) {
+	clock := newTestClock()
+	l := NewMemoryLimiter(map[LimitKind]Limit{
+		LimitUser: {Max: 2, Window: time.Minute, Lockout: time.Minute},
+		LimitIP:   {Max: 2, Window: time.Minute, Lockout: time.Minute},
+	})
+	l.now = clock.now
+	w := newLimitedWebAuthn(t, l)
+	key := newAssertionKey(t)
+
+	failed := key.loginData(t, "c")
+	failed.Options.Challenge = "other"
+	if _, err := w.ValidateLoginData(failed); !errors.Is(err, ErrChallengeMismatch) {
+		t.Fatalf("ValidateLoginData() error = %v, want %v", err, ErrChallengeMismatch)
+	}
+	if _, err := w.ValidateLoginData(key.loginData(t, "c")); err != nil {
+		t.Fatalf("ValidateLoginData: %v", err)
+	}
+	if _, err := w.ValidateLoginData(failed); !errors.Is(err, ErrChallengeMismatch) {
+		t.Fatalf("ValidateLoginData() error = %v, want %v", err, ErrChallengeMismatch)
+	}
+	if err := check(l, userKey("AQID")); err != nil {
+		t.Errorf("user locked out after fail, success, fail: %v", err)
+	}
+	if err := check(l, ipKey("203.0.113.7")); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("IP check error = %v, want %v", err, ErrRateLimited)
+	}
+}
@@ -7,8 +7,9 @@ import (
 )
 
 // BeginLogin generates options for the login process using a pre-generated challenge.
+// Options (e.g., WithPRF) can request additional extensions.
 // Returns options (with base64url challenge) or an error.
-func (w *WebAuthn) BeginLogin(allowedCredentialIDs []string) (*PublicKeyCredentialRequestOptions, error) {
+func (w *WebAuthn) BeginLogin(allowedCredentialIDs []string, opts ...Option) (*PublicKeyCredentialRequestOptions, error) {
 	if w == nil {
 		return nil, errors.New("WebAuthn instance is nil")
 	}
@@ -32,6 +33,9 @@ func (w *WebAuthn) BeginLogin(allowedCredentialIDs []string) (*PublicKeyCredenti
 		AllowCredentials: allowedCredentials,
 		UserVerification: w.Config.UserVerification,
 	}
+	if err := applyLoginOptions(options, opts); err != nil {
+		return nil, err
+	}
 
 	return options, nil
 }
@@ -47,7 +51,34 @@ func (w *WebAuthn) FinishLogin(data *LoginData) (*LoginResult, error) {
 	}
 
 	return &LoginResult{
-		NewSignCount: res.NewSignCount,
-		UserVerified: res.UserVerified,
+		NewSignCount:   res.NewSignCount,
+		UserVerified:   res.UserVerified,
+		RPID:           res.RPID,
+		Extensions:     res.Extensions,
+		BackupEligible: res.BackupEligible,
+		BackedUp:       res.BackedUp,
+		Compliance:     res.Compliance,
+		PRF:            res.Extensions.PRF,
+		LargeBlob:      res.Extensions.LargeBlob,
 	}, nil
 }
+
+// Authentication method references (RFC 8176) reported by LoginResult.AMR
+const (
+	AMRHardwareKey = "hwk"  // Device-bound credential, the key can't leave the authenticator
+	AMRSoftwareKey = "swk"  // Backup eligible credential, the key may be synced between devices
+	AMRUser        = "user" // User verified by the authenticator, e.g., with a PIN or biometrics
+)
+
+// AMR returns the authentication method references of the login, e.g., for session tokens.
+// Whether the key is hardware-bound is derived from the backup eligibility flag, it isn't attested.
+func (r *LoginResult) AMR() []string {
+	amr := []string{AMRHardwareKey}
+	if r.BackupEligible {
+		amr[0] = AMRSoftwareKey
+	}
+	if r.UserVerified {
+		amr = append(amr, AMRUser)
+	}
+	return amr
+}
@@ -0,0 +1,180 @@
+package webauthn
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// MultiRP serves several relying parties at once, e.g., one per customer domain of a SaaS.
+// Every tenant gets its own WebAuthn instance, so origins, timeouts and policies never leak between them.
+type MultiRP struct {
+	tenants map[string]*WebAuthn
+	origins map[parsedOriginData]string // Origin -> tenant key, for routing by origin
+}
+
+// RPSelector picks the tenant a ceremony runs for.
+// Tenant takes precedence; otherwise the tenant owning Origin is used.
+type RPSelector struct {
+	Tenant string // Tenant key as passed to NewMultiRP
+	Origin string // Request origin (e.g., "https://login.customer.com")
+}
+
+// NewMultiRP creates a WebAuthn instance for every tenant configuration, keyed by tenant key.
+// Each configuration is validated like in New. Origins must be unique across tenants,
+// so that routing by origin is never ambiguous.
+func NewMultiRP(configs map[string]*Config) (*MultiRP, error) {
+	if len(configs) == 0 {
+		return nil, ErrNoTenants
+	}
+
+	m := &MultiRP{
+		tenants: make(map[string]*WebAuthn, len(configs)),
+		origins: make(map[parsedOriginData]string),
+	}
+	for key, config := range configs {
+		if key == "" {
+			return nil, ErrEmptyTenantKey
+		}
+		if config == nil {
+			return nil, fmt.Errorf("tenant %s: %w", key, ErrNilConfig)
+		}
+		// New copies the config, so tenants sharing one *Config can't affect each other later
+		w, err := New(config)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", key, err)
+		}
+		for _, origin := range append(append([]parsedOriginData{}, w.parsedRPOrigins...), w.parsedRelatedOrigins...) {
+			if owner, exists := m.origins[origin]; exists && owner != key {
+				return nil, fmt.Errorf("%w: %s://%s is used by tenants %s and %s", ErrDuplicateTenantOrigin, origin.scheme, origin.host, owner, key)
+			}
+			m.origins[origin] = key
+		}
+		m.tenants[key] = w
+	}
+	return m, nil
+}
+
+// Tenant returns the WebAuthn instance registered under the key.
+func (m *MultiRP) Tenant(key string) (*WebAuthn, error) {
+	if m == nil {
+		return nil, ErrNilInstance
+	}
+	w, ok := m.tenants[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTenant, key)
+	}
+	return w, nil
+}
+
+// TenantForOrigin returns the key and WebAuthn instance of the tenant that allows the origin.
+func (m *MultiRP) TenantForOrigin(origin string) (key string, w *WebAuthn, err error) {
+	if m == nil {
+		return "", nil, ErrNilInstance
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w %s: %v", ErrParsingOrigin, origin, err)
+	}
+	key, ok := m.origins[newParsedOrigin(u)]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %s", ErrNoTenantForOrigin, origin)
+	}
+	return key, m.tenants[key], nil
+}
+
+// Select resolves the selector to a tenant's WebAuthn instance.
+func (m *MultiRP) Select(sel RPSelector) (*WebAuthn, error) {
+	switch {
+	case sel.Tenant != "":
+		return m.Tenant(sel.Tenant)
+	case sel.Origin != "":
+		_, w, err := m.TenantForOrigin(sel.Origin)
+		return w, err
+	default:
+		return nil, ErrEmptyRPSelector
+	}
+}
+
+// selectByClientData resolves the selector, falling back to the origin reported in the client data.
+func (m *MultiRP) selectByClientData(sel RPSelector, clientDataJSON string) (*WebAuthn, error) {
+	if sel.Tenant == "" && sel.Origin == "" {
+		var clientData ClientData
+		if _, err := clientData.ParseWithB64(clientDataJSON); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFailedParseClientData, err)
+		}
+		sel.Origin = clientData.RPOrigin
+	}
+	return m.Select(sel)
+}
+
+// BeginRegistration starts the registration ceremony for the selected tenant.
+func (m *MultiRP) BeginRegistration(sel RPSelector, user UserEntity, opts ...Option) (*BeginRegistrationOptions, error) {
+	w, err := m.Select(sel)
+	if err != nil {
+		return nil, err
+	}
+	return w.BeginRegistration(user, opts...)
+}
+
+// FinishRegistration completes the registration ceremony for the selected tenant.
+// An empty selector routes by the origin in the client data, which the tenant then verifies as usual.
+func (m *MultiRP) FinishRegistration(sel RPSelector, data RegistrationData) (*RegistrationResult, error) {
+	w, err := m.selectByClientData(sel, data.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	return w.FinishRegistration(data)
+}
+
+// BeginLogin starts the login ceremony for the selected tenant.
+func (m *MultiRP) BeginLogin(sel RPSelector, allowedCredentialIDs []string, opts ...Option) (*PublicKeyCredentialRequestOptions, error) {
+	w, err := m.Select(sel)
+	if err != nil {
+		return nil, err
+	}
+	return w.BeginLogin(allowedCredentialIDs, opts...)
+}
+
+// BeginLoginForUser starts an enumeration-resistant login ceremony for the selected tenant.
+func (m *MultiRP) BeginLoginForUser(sel RPSelector, username string, allowedCredentialIDs []string, opts ...Option) (*PublicKeyCredentialRequestOptions, error) {
+	w, err := m.Select(sel)
+	if err != nil {
+		return nil, err
+	}
+	return w.BeginLoginForUser(username, allowedCredentialIDs, opts...)
+}
+
+// FinishLogin completes the login ceremony for the selected tenant.
+// An empty selector routes by the origin in the client data, which the tenant then verifies as usual.
+func (m *MultiRP) FinishLogin(sel RPSelector, data *LoginData) (*LoginResult, error) {
+	if data == nil {
+		return nil, ErrNilLoginData
+	}
+	w, err := m.selectByClientData(sel, data.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	return w.FinishLogin(data)
+}
+
+// BeginConditionalLogin starts a passkey autofill login for the selected tenant.
+func (m *MultiRP) BeginConditionalLogin(sel RPSelector, opts ...Option) (*CredentialRequestOptions, error) {
+	w, err := m.Select(sel)
+	if err != nil {
+		return nil, err
+	}
+	return w.BeginConditionalLogin(opts...)
+}
+
+// FinishConditionalLogin completes a passkey autofill login for the selected tenant.
+// An empty selector routes by the origin in the client data, which the tenant then verifies as usual.
+func (m *MultiRP) FinishConditionalLogin(sel RPSelector, data *LoginData, resolve CredentialResolver) (*LoginResult, error) {
+	if data == nil {
+		return nil, ErrNilLoginData
+	}
+	w, err := m.selectByClientData(sel, data.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	return w.FinishConditionalLogin(data, resolve)
+}
@@ -0,0 +1,129 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// authorize handles authorization requests. Errors in the client or redirect URI are shown to the user, all others
+// are sent back to the client, as OAuth requires.
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	client, err := p.config.Clients.ClientByID(r.Context(), query.Get("client_id"))
+	if errors.Is(err, ErrClientNotFound) {
+		p.writeError(w, r, invalidRequest("Unknown client", err))
+		return
+	} else if err != nil {
+		p.writeError(w, r, serverError("Failed to look up client", err))
+		return
+	}
+	redirectURI := query.Get("redirect_uri")
+	if !contains(client.RedirectURIs, redirectURI) {
+		p.writeError(w, r, invalidRequest("Redirect URI not registered for the client", nil))
+		return
+	}
+
+	state := query.Get("state")
+	if query.Get("response_type") != "code" {
+		p.redirectError(w, r, redirectURI, state, &Error{Code: CodeUnsupportedResponseType, Description: "Only the code response type is supported"})
+		return
+	}
+	scopes := strings.Fields(query.Get("scope"))
+	if !contains(scopes, ScopeOpenID) {
+		p.redirectError(w, r, redirectURI, state, &Error{Code: CodeInvalidScope, Description: "The openid scope is required"})
+		return
+	}
+	// PKCE is required for all clients, plain challenges are not accepted
+	codeChallenge := query.Get("code_challenge")
+	if query.Get("code_challenge_method") != "S256" || len(codeChallenge) != base64.RawURLEncoding.EncodedLen(32) {
+		p.redirectError(w, r, redirectURI, state, invalidRequest("A S256 code_challenge is required", nil))
+		return
+	}
+
+	claims, err := p.config.Sessions.FromRequest(r)
+	if err != nil {
+		if query.Get("prompt") == "none" {
+			p.redirectError(w, r, redirectURI, state, &Error{Code: CodeLoginRequired, Description: "No session", Err: err})
+			return
+		}
+		http.Redirect(w, r, p.loginURL(r), http.StatusFound)
+		return
+	}
+
+	code, err := newCode()
+	if err != nil {
+		p.redirectError(w, r, redirectURI, state, serverError("Failed to generate code", err))
+		return
+	}
+	grant := Grant{
+		ClientID:      client.ID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		Nonce:         query.Get("nonce"),
+		CodeChallenge: codeChallenge,
+		UserID:        claims.UserID,
+		CredentialID:  claims.CredentialID,
+		UserVerified:  claims.UserVerified,
+		AMR:           claims.AMR,
+		AuthTime:      claims.AuthTime,
+	}
+	if err := p.config.Codes.SaveCode(r.Context(), code, grant, time.Now().Add(p.config.CodeLifetime)); err != nil {
+		p.redirectError(w, r, redirectURI, state, serverError("Failed to save code", err))
+		return
+	}
+
+	params := url.Values{"code": {code}, "iss": {p.config.Issuer}}
+	if state != "" {
+		params.Set("state", state)
+	}
+	http.Redirect(w, r, withQuery(redirectURI, params), http.StatusFound)
+}
+
+// redirectError sends the error to the client's redirect URI.
+func (p *Provider) redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state string, err *Error) {
+	if p.config.OnError != nil {
+		p.config.OnError(r, err)
+	}
+	params := url.Values{"error": {err.Code}, "iss": {p.config.Issuer}}
+	if err.Description != "" {
+		params.Set("error_description", err.Description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	http.Redirect(w, r, withQuery(redirectURI, params), http.StatusFound)
+}
+
+// loginURL returns the login page URL, with the authorization request to come back to in return_to.
+func (p *Provider) loginURL(r *http.Request) string {
+	returnTo := p.config.Issuer + "/authorize?" + r.URL.RawQuery
+	return withQuery(p.config.LoginURL, url.Values{"return_to": {returnTo}})
+}
+
+// withQuery adds the parameters to the URL's query.
+func withQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// newCode generates a random authorization code.
+func newCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
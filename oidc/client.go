@@ -0,0 +1,168 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/MrBoombastic/WebAuthn2Go/jwt"
+)
+
+// RelyingPartyConfig configures a RelyingParty.
+type RelyingPartyConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // Empty for public clients
+	RedirectURI  string
+	Scopes       []string     // Defaults to openid
+	HTTPClient   *http.Client // Defaults to http.DefaultClient, e.g., an httptest.Server client in tests
+}
+
+// RelyingParty is a minimal client of the provider, for services logging users in through it and for tests.
+type RelyingParty struct {
+	config   RelyingPartyConfig
+	metadata Metadata
+	verifier *jwt.Verifier
+}
+
+// NewRelyingParty fetches the provider metadata and signing keys.
+func NewRelyingParty(ctx context.Context, config RelyingPartyConfig) (*RelyingParty, error) {
+	if config.ClientID == "" {
+		return nil, ErrMissingClientID
+	}
+	if config.RedirectURI == "" {
+		return nil, ErrMissingRedirectURI
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{ScopeOpenID}
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	rp := &RelyingParty{config: config}
+
+	if err := rp.getJSON(ctx, strings.TrimSuffix(config.Issuer, "/")+"/.well-known/openid-configuration", &rp.metadata); err != nil {
+		return nil, err
+	}
+	if rp.metadata.Issuer != config.Issuer {
+		return nil, fmt.Errorf("%w: %s", ErrIssuerMismatch, rp.metadata.Issuer)
+	}
+	var jwks jwt.JWKS
+	if err := rp.getJSON(ctx, rp.metadata.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	verifier, err := jwt.NewVerifier(jwt.VerifierConfig{Keys: jwks, Issuer: config.Issuer, Audience: config.ClientID})
+	if err != nil {
+		return nil, err
+	}
+	rp.verifier = verifier
+	return rp, nil
+}
+
+// AuthCodeURL returns the URL to send the user to, and the PKCE code verifier to keep for Exchange.
+// state and nonce should be random and kept in the user's session too.
+func (rp *RelyingParty) AuthCodeURL(state, nonce string) (authURL, codeVerifier string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	codeVerifier = base64.RawURLEncoding.EncodeToString(b)
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {rp.config.ClientID},
+		"redirect_uri":          {rp.config.RedirectURI},
+		"scope":                 {strings.Join(rp.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	return withQuery(rp.metadata.AuthorizationEndpoint, params), codeVerifier, nil
+}
+
+// Exchange redeems the code returned to the redirect URI.
+func (rp *RelyingParty) Exchange(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {rp.config.RedirectURI},
+		"code_verifier": {codeVerifier},
+	}
+	if rp.config.ClientSecret == "" {
+		form.Set("client_id", rp.config.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rp.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rp.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(rp.config.ClientID), url.QueryEscape(rp.config.ClientSecret))
+	}
+	resp, err := rp.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr Error
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Code != "" {
+			oauthErr.Status = resp.StatusCode
+			return nil, fmt.Errorf("%w: %w", ErrTokenRequest, &oauthErr)
+		}
+		return nil, fmt.Errorf("%w: status %d", ErrTokenRequest, resp.StatusCode)
+	}
+	var tokens TokenResponse
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	if tokens.IDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+	return &tokens, nil
+}
+
+// VerifyIDToken validates the ID token and checks its nonce.
+func (rp *RelyingParty) VerifyIDToken(idToken, nonce string) (*IDTokenClaims, error) {
+	var claims IDTokenClaims
+	if err := rp.verifier.VerifyInto(idToken, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+	return &claims, nil
+}
+
+// getJSON fetches a JSON document.
+func (rp *RelyingParty) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDiscoveryFailed, err)
+	}
+	resp, err := rp.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDiscoveryFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s: status %d", ErrDiscoveryFailed, url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrDiscoveryFailed, url, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package oidc
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrMissingIssuer      = errors.New("issuer must be an absolute HTTPS URL")
+	ErrNilSessions        = errors.New("session manager cannot be nil")
+	ErrNilClientStore     = errors.New("client store cannot be nil")
+	ErrMissingLoginURL    = errors.New("login URL is required")
+	ErrClientNotFound     = errors.New("client not found")
+	ErrCodeNotFound       = errors.New("authorization code not found, expired or already used")
+	ErrDiscoveryFailed    = errors.New("failed to fetch provider metadata")
+	ErrIssuerMismatch     = errors.New("provider metadata issuer mismatch")
+	ErrTokenRequest       = errors.New("token request failed")
+	ErrMissingIDToken     = errors.New("token response has no ID token")
+	ErrNonceMismatch      = errors.New("ID token nonce mismatch")
+	ErrMissingClientID    = errors.New("client ID is required")
+	ErrMissingRedirectURI = errors.New("redirect URI is required")
+)
+
+// Error codes of OAuth 2.0 (RFC 6749) and OpenID Connect error responses.
+const (
+	CodeInvalidRequest          = "invalid_request"
+	CodeInvalidClient           = "invalid_client"
+	CodeInvalidGrant            = "invalid_grant"
+	CodeUnauthorizedClient      = "unauthorized_client"
+	CodeUnsupportedGrantType    = "unsupported_grant_type"
+	CodeUnsupportedResponseType = "unsupported_response_type"
+	CodeInvalidScope            = "invalid_scope"
+	CodeLoginRequired           = "login_required"
+	CodeServerError             = "server_error"
+)
+
+// Error is an OAuth error, returned as {"error": ..., "error_description": ...} or as redirect parameters.
+// Err holds the cause, it's passed to Config.OnError but never sent to clients.
+type Error struct {
+	Status      int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Err         error  `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Description + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Description
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func invalidRequest(description string, err error) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Description: description, Err: err}
+}
+
+func invalidGrant(description string, err error) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidGrant, Description: description, Err: err}
+}
+
+func invalidClient(err error) *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: CodeInvalidClient, Description: "Client authentication failed", Err: err}
+}
+
+func serverError(description string, err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeServerError, Description: description, Err: err}
+}
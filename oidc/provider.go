@@ -0,0 +1,217 @@
+// Package oidc is a minimal OpenID Connect provider whose user authentication is a passkey login.
+// It implements the authorization code flow with PKCE (S256) for internal identity providers.
+//
+// Endpoints, relative to Config.Issuer:
+//
+//	/.well-known/openid-configuration  GET   provider metadata
+//	/authorize                         GET   authorization endpoint, redirects to Config.LoginURL without a session
+//	/token                             POST  token endpoint (authorization_code grant)
+//	/jwks                              GET   signing keys
+//	/webauthn/...                      POST  passkey ceremonies, see the httpapi package
+//
+// The login page at Config.LoginURL runs the passkey login against /webauthn/login/begin and /webauthn/login/finish,
+// which starts a session (see the session package), and then navigates to its return_to parameter.
+package oidc
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/httpapi"
+	"github.com/MrBoombastic/WebAuthn2Go/jwt"
+	"github.com/MrBoombastic/WebAuthn2Go/session"
+)
+
+const (
+	defaultCodeLifetime  = time.Minute
+	defaultTokenLifetime = 15 * time.Minute
+	// maxTokenRequestBytes limits token request bodies.
+	maxTokenRequestBytes = 16 << 10
+)
+
+// Scopes
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile" // Adds name and preferred_username to ID tokens
+)
+
+// Config configures the Provider.
+type Config struct {
+	// Issuer is the HTTPS URL the provider is served at, without a trailing slash (e.g., "https://login.example.com").
+	Issuer string
+	// Ceremonies configures the passkey endpoints under /webauthn. OnLogin is wrapped to start the session.
+	Ceremonies httpapi.Config
+	// Sessions keeps users logged in between authorization requests.
+	Sessions *session.Manager
+	// Keys sign ID and access tokens, see jwt.Config.Keys.
+	Keys    []jwt.Key
+	Clients ClientStore
+	Codes   CodeStore // Defaults to a MemoryStore
+	// LoginURL is the login page users without a session are sent to, with the authorization request in return_to.
+	LoginURL string
+	// CodeLifetime is the lifetime of authorization codes, defaults to 1 minute.
+	CodeLifetime time.Duration
+	// TokenLifetime is the lifetime of ID and access tokens, defaults to 15 minutes.
+	TokenLifetime time.Duration
+	// ACR derives the "acr" claim, see jwt.Config.ACR. The result is rebuilt from the session, only UserID,
+	// CredentialID, UserVerified and BackupEligible are set. Optional.
+	ACR func(result *webauthn.LoginResult) string
+	// OnError is called with every error sent to a client, e.g., for logging. Optional.
+	OnError func(r *http.Request, err *Error)
+}
+
+// Provider serves the OpenID Connect endpoints, see the package documentation.
+type Provider struct {
+	config     Config
+	ceremonies http.Handler
+	tokens     *jwt.Issuer
+}
+
+// Metadata is the provider metadata served at /.well-known/openid-configuration.
+type Metadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// New creates a Provider. Issuer, Ceremonies (see httpapi.New), Sessions, Keys, Clients and LoginURL are required.
+func New(config Config) (*Provider, error) {
+	if u, err := url.Parse(config.Issuer); err != nil || u.Scheme != "https" || u.Host == "" || strings.HasSuffix(config.Issuer, "/") {
+		return nil, ErrMissingIssuer
+	}
+	if config.Sessions == nil {
+		return nil, ErrNilSessions
+	}
+	if config.Clients == nil {
+		return nil, ErrNilClientStore
+	}
+	if config.LoginURL == "" {
+		return nil, ErrMissingLoginURL
+	}
+	if config.Codes == nil {
+		config.Codes = NewMemoryStore()
+	}
+	if config.CodeLifetime <= 0 {
+		config.CodeLifetime = defaultCodeLifetime
+	}
+	if config.TokenLifetime <= 0 {
+		config.TokenLifetime = defaultTokenLifetime
+	}
+
+	// Access tokens are meant for the provider itself, ID tokens get the client as audience
+	tokens, err := jwt.New(jwt.Config{
+		Issuer:   config.Issuer,
+		Audience: []string{config.Issuer},
+		Lifetime: config.TokenLifetime,
+		Keys:     config.Keys,
+		ACR:      config.ACR,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{config: config, tokens: tokens}
+	ceremonies := config.Ceremonies
+	onLogin := ceremonies.OnLogin
+	ceremonies.OnLogin = func(w http.ResponseWriter, r *http.Request, user webauthn.UserEntity, result *webauthn.LoginResult) error {
+		if onLogin != nil {
+			if err := onLogin(w, r, user, result); err != nil {
+				return err
+			}
+		}
+		token, claims, err := config.Sessions.Issue(result)
+		if err != nil {
+			return err
+		}
+		config.Sessions.SetCookie(w, token, claims)
+		return nil
+	}
+	handler, err := httpapi.New(ceremonies)
+	if err != nil {
+		return nil, err
+	}
+	p.ceremonies = http.StripPrefix("/webauthn", handler)
+	return p, nil
+}
+
+// ServeHTTP routes the request to the endpoint. Mount it with http.StripPrefix if Issuer has a path.
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		p.serveGet(w, r, func() { writeJSON(w, http.StatusOK, p.Metadata()) })
+	case "/jwks":
+		p.serveGet(w, r, func() { writeJSON(w, http.StatusOK, p.tokens.JWKS()) })
+	case "/authorize":
+		p.serveGet(w, r, func() { p.authorize(w, r) })
+	case "/token":
+		p.token(w, r)
+	default:
+		if strings.HasPrefix(r.URL.Path, "/webauthn/") {
+			p.ceremonies.ServeHTTP(w, r)
+			return
+		}
+		p.writeError(w, r, &Error{Status: http.StatusNotFound, Code: CodeInvalidRequest, Description: "Not found"})
+	}
+}
+
+// Metadata returns the provider metadata.
+func (p *Provider) Metadata() Metadata {
+	var algs []string
+	for _, key := range p.tokens.JWKS().Keys {
+		if !contains(algs, key.Alg) {
+			algs = append(algs, key.Alg)
+		}
+	}
+	return Metadata{
+		Issuer:                            p.config.Issuer,
+		AuthorizationEndpoint:             p.config.Issuer + "/authorize",
+		TokenEndpoint:                     p.config.Issuer + "/token",
+		JWKSURI:                           p.config.Issuer + "/jwks",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "amr", "acr", "azp", "name", "preferred_username"},
+	}
+}
+
+// serveGet calls serve for GET (and HEAD) requests only.
+func (p *Provider) serveGet(w http.ResponseWriter, r *http.Request, serve func()) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		p.writeError(w, r, &Error{Status: http.StatusMethodNotAllowed, Code: CodeInvalidRequest, Description: "Method not allowed"})
+		return
+	}
+	serve()
+}
+
+func (p *Provider) writeError(w http.ResponseWriter, r *http.Request, err *Error) {
+	if p.config.OnError != nil {
+		p.config.OnError(r, err)
+	}
+	writeJSON(w, err.Status, err)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,336 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/httpapi"
+	"github.com/MrBoombastic/WebAuthn2Go/jwt"
+	"github.com/MrBoombastic/WebAuthn2Go/session"
+)
+
+const (
+	testRedirectURI = "https://wiki.example.com/callback"
+	testLoginURL    = "https://login.example.com/login"
+)
+
+var testUser = webauthn.UserEntity{ID: []byte{1, 2, 3}, Name: "alice", DisplayName: "Alice"}
+
+// testProvider is a Provider served over TLS, with a session manager to log users in without a passkey.
+type testProvider struct {
+	server   *httptest.Server
+	sessions *session.Manager
+}
+
+func newTestProvider(t *testing.T, configure func(config *Config)) *testProvider {
+	t.Helper()
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:             "example.com",
+		RPDisplayName:    "Example",
+		RPOrigins:        []string{"https://example.com"},
+		Timeout:          60000,
+		UserVerification: webauthn.UVPreferred,
+		Attestation:      webauthn.AttestationNone,
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New: %v", err)
+	}
+	users := httpapi.NewMemoryStore()
+	if err := users.CreateUser(context.Background(), testUser); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	sessions, err := session.New(session.Config{Keys: []session.Key{{ID: "s1", Secret: make([]byte, 32)}}})
+	if err != nil {
+		t.Fatalf("session.New: %v", err)
+	}
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	// The issuer is the server URL, which is only known once the server runs
+	var provider *Provider
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	config := Config{
+		Issuer:     server.URL,
+		Ceremonies: httpapi.Config{WebAuthn: w, Users: users, Credentials: users},
+		Sessions:   sessions,
+		Keys:       []jwt.Key{{ID: "k1", Signer: signer}},
+		Clients: NewMemoryStore(
+			Client{ID: "wiki", RedirectURIs: []string{testRedirectURI}},
+			Client{ID: "backend", Secret: "s3cret", RedirectURIs: []string{testRedirectURI}},
+		),
+		LoginURL: testLoginURL,
+	}
+	if configure != nil {
+		configure(&config)
+	}
+	if provider, err = New(config); err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return &testProvider{server: server, sessions: sessions}
+}
+
+// client returns an HTTP client trusting the server that doesn't follow redirects.
+func (tp *testProvider) client() *http.Client {
+	client := *tp.server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &client
+}
+
+func (tp *testProvider) relyingParty(t *testing.T, config RelyingPartyConfig) *RelyingParty {
+	t.Helper()
+	config.Issuer = tp.server.URL
+	config.RedirectURI = testRedirectURI
+	config.HTTPClient = tp.client()
+	if config.ClientID == "" {
+		config.ClientID = "wiki"
+	}
+	rp, err := NewRelyingParty(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewRelyingParty: %v", err)
+	}
+	return rp
+}
+
+// sessionCookie issues a session as if the user had logged in with a passkey.
+func (tp *testProvider) sessionCookie(t *testing.T, result *webauthn.LoginResult) *http.Cookie {
+	t.Helper()
+	token, claims, err := tp.sessions.Issue(result)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	tp.sessions.SetCookie(rec, token, claims)
+	return rec.Result().Cookies()[0]
+}
+
+// authorize requests authURL and returns the redirect location.
+func (tp *testProvider) authorize(t *testing.T, authURL string, cookie *http.Cookie) *url.URL {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, authURL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := tp.client().Do(req)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("Location: %v", err)
+	}
+	return location
+}
+
+func TestDiscovery(t *testing.T) {
+	tp := newTestProvider(t, nil)
+	rp := tp.relyingParty(t, RelyingPartyConfig{})
+	metadata := rp.metadata
+	if metadata.Issuer != tp.server.URL || metadata.TokenEndpoint != tp.server.URL+"/token" || metadata.JWKSURI != tp.server.URL+"/jwks" {
+		t.Errorf("metadata endpoints = %+v", metadata)
+	}
+	if !slices.Equal(metadata.CodeChallengeMethodsSupported, []string{"S256"}) {
+		t.Errorf("code_challenge_methods_supported = %v, want [S256]", metadata.CodeChallengeMethodsSupported)
+	}
+	if !slices.Equal(metadata.IDTokenSigningAlgValuesSupported, []string{jwt.AlgES256}) {
+		t.Errorf("id_token_signing_alg_values_supported = %v, want [ES256]", metadata.IDTokenSigningAlgValuesSupported)
+	}
+
+	_, err := NewRelyingParty(context.Background(), RelyingPartyConfig{
+		Issuer: tp.server.URL + "/other", ClientID: "wiki", RedirectURI: testRedirectURI, HTTPClient: tp.client(),
+	})
+	if !errors.Is(err, ErrDiscoveryFailed) {
+		t.Errorf("NewRelyingParty() for a wrong issuer error = %v, want %v", err, ErrDiscoveryFailed)
+	}
+}
+
+func TestAuthorizeWithoutSession(t *testing.T) {
+	tp := newTestProvider(t, nil)
+	rp := tp.relyingParty(t, RelyingPartyConfig{})
+	authURL, _, err := rp.AuthCodeURL("state1", "nonce1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	location := tp.authorize(t, authURL, nil)
+	if got := location.Scheme + "://" + location.Host + location.Path; got != testLoginURL {
+		t.Fatalf("redirected to %s, want the login page", location)
+	}
+	if returnTo := location.Query().Get("return_to"); !strings.HasPrefix(returnTo, tp.server.URL+"/authorize?") {
+		t.Errorf("return_to = %s, want the authorization request", returnTo)
+	}
+
+	location = tp.authorize(t, authURL+"&prompt=none", nil)
+	if location.Query().Get("error") != CodeLoginRequired || location.Query().Get("state") != "state1" {
+		t.Errorf("prompt=none redirected to %s, want login_required", location)
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   RelyingPartyConfig
+		result   *webauthn.LoginResult
+		acr      func(*webauthn.LoginResult) string
+		wantAMR  []string
+		wantACR  string
+		wantName string
+	}{
+		{
+			name:    "public client, device-bound key",
+			config:  RelyingPartyConfig{},
+			result:  &webauthn.LoginResult{UserID: testUser.ID, CredentialID: "BAUG", UserVerified: true},
+			wantAMR: []string{webauthn.AMRHardwareKey, webauthn.AMRUser},
+			wantACR: jwt.ACRPhishingResistantHardware,
+		},
+		{
+			name:     "confidential client with profile, synced key",
+			config:   RelyingPartyConfig{ClientID: "backend", ClientSecret: "s3cret", Scopes: []string{ScopeOpenID, ScopeProfile}},
+			result:   &webauthn.LoginResult{UserID: testUser.ID, CredentialID: "BAUG", BackupEligible: true},
+			wantAMR:  []string{webauthn.AMRSoftwareKey},
+			wantACR:  jwt.ACRSingleFactor,
+			wantName: testUser.DisplayName,
+		},
+		{
+			name:   "custom acr",
+			config: RelyingPartyConfig{},
+			result: &webauthn.LoginResult{UserID: testUser.ID, CredentialID: "BAUG", UserVerified: true},
+			acr: func(result *webauthn.LoginResult) string {
+				if result.UserVerified && result.CredentialID == "BAUG" {
+					return "urn:example:gold"
+				}
+				return "urn:example:silver"
+			},
+			wantAMR: []string{webauthn.AMRHardwareKey, webauthn.AMRUser},
+			wantACR: "urn:example:gold",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp := newTestProvider(t, func(config *Config) { config.ACR = tt.acr })
+			rp := tp.relyingParty(t, tt.config)
+			authURL, verifier, err := rp.AuthCodeURL("state1", "nonce1")
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+
+			location := tp.authorize(t, authURL, tp.sessionCookie(t, tt.result))
+			if got := location.Scheme + "://" + location.Host + location.Path; got != testRedirectURI {
+				t.Fatalf("redirected to %s, want the client", location)
+			}
+			query := location.Query()
+			if query.Get("state") != "state1" || query.Get("iss") != tp.server.URL || query.Get("code") == "" {
+				t.Fatalf("redirect parameters = %v", query)
+			}
+
+			tokens, err := rp.Exchange(context.Background(), query.Get("code"), verifier)
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if tokens.TokenType != "Bearer" || tokens.AccessToken == "" || tokens.ExpiresIn != int64(defaultTokenLifetime/time.Second) {
+				t.Errorf("token response = %+v", tokens)
+			}
+
+			claims, err := rp.VerifyIDToken(tokens.IDToken, "nonce1")
+			if err != nil {
+				t.Fatalf("VerifyIDToken: %v", err)
+			}
+			if claims.Subject != "AQID" || claims.AuthorizedParty != rp.config.ClientID || claims.AuthTime == 0 {
+				t.Errorf("ID token claims = %+v", claims)
+			}
+			if !slices.Equal(claims.AMR, tt.wantAMR) {
+				t.Errorf("amr = %v, want %v", claims.AMR, tt.wantAMR)
+			}
+			if claims.ACR != tt.wantACR {
+				t.Errorf("acr = %s, want %s", claims.ACR, tt.wantACR)
+			}
+			if claims.Name != tt.wantName {
+				t.Errorf("name = %q, want %q", claims.Name, tt.wantName)
+			}
+			if _, err := rp.VerifyIDToken(tokens.IDToken, "nonce2"); !errors.Is(err, ErrNonceMismatch) {
+				t.Errorf("VerifyIDToken() with another nonce error = %v, want %v", err, ErrNonceMismatch)
+			}
+
+			// Codes can be redeemed once
+			_, err = rp.Exchange(context.Background(), query.Get("code"), verifier)
+			var oauthErr *Error
+			if !errors.As(err, &oauthErr) || oauthErr.Code != CodeInvalidGrant {
+				t.Errorf("Exchange() of a used code error = %v, want %s", err, CodeInvalidGrant)
+			}
+		})
+	}
+}
+
+func TestTokenRejects(t *testing.T) {
+	tp := newTestProvider(t, nil)
+	cookie := tp.sessionCookie(t, &webauthn.LoginResult{UserID: testUser.ID})
+	tests := []struct {
+		name     string
+		config   RelyingPartyConfig
+		verifier func(verifier string) string
+		code     string
+	}{
+		{"wrong code verifier", RelyingPartyConfig{}, func(string) string { return strings.Repeat("a", 43) }, CodeInvalidGrant},
+		{"short code verifier", RelyingPartyConfig{}, func(v string) string { return v[:42] }, CodeInvalidGrant},
+		{"wrong client secret", RelyingPartyConfig{ClientID: "backend", ClientSecret: "wrong"}, nil, CodeInvalidClient},
+		{"missing client secret", RelyingPartyConfig{ClientID: "backend"}, nil, CodeInvalidClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := tp.relyingParty(t, tt.config)
+			authURL, verifier, err := rp.AuthCodeURL("state1", "nonce1")
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+			code := tp.authorize(t, authURL, cookie).Query().Get("code")
+			if tt.verifier != nil {
+				verifier = tt.verifier(verifier)
+			}
+			_, err = rp.Exchange(context.Background(), code, verifier)
+			var oauthErr *Error
+			if !errors.As(err, &oauthErr) || oauthErr.Code != tt.code {
+				t.Fatalf("Exchange() error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestIDTokenForOtherClient(t *testing.T) {
+	tp := newTestProvider(t, nil)
+	wiki := tp.relyingParty(t, RelyingPartyConfig{})
+	backend := tp.relyingParty(t, RelyingPartyConfig{ClientID: "backend", ClientSecret: "s3cret"})
+	authURL, verifier, err := wiki.AuthCodeURL("state1", "nonce1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := tp.authorize(t, authURL, tp.sessionCookie(t, &webauthn.LoginResult{UserID: testUser.ID})).Query().Get("code")
+	tokens, err := wiki.Exchange(context.Background(), code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := backend.VerifyIDToken(tokens.IDToken, "nonce1"); !errors.Is(err, jwt.ErrAudienceMismatch) {
+		t.Fatalf("VerifyIDToken() by another client error = %v, want %v", err, jwt.ErrAudienceMismatch)
+	}
+}
@@ -0,0 +1,114 @@
+package oidc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Client is a registered relying party.
+type Client struct {
+	ID string
+	// Secret authenticates confidential clients. Empty for public clients (e.g., SPAs or mobile apps),
+	// which rely on PKCE alone.
+	Secret       string
+	RedirectURIs []string // Exact redirect URIs the client may use
+}
+
+// ClientStore looks up registered clients.
+type ClientStore interface {
+	// ClientByID returns the client, or ErrClientNotFound.
+	ClientByID(ctx context.Context, id string) (*Client, error)
+}
+
+// Grant is what an authorization code stands for until it's redeemed at the token endpoint.
+type Grant struct {
+	ClientID      string
+	RedirectURI   string
+	Scopes        []string
+	Nonce         string
+	CodeChallenge string // S256 PKCE challenge
+	UserID        []byte
+	CredentialID  string // Base64url
+	UserVerified  bool
+	AMR           []string
+	AuthTime      int64 // Unix time of the passkey login
+}
+
+// CodeStore keeps authorization codes between the authorize and the token endpoint.
+type CodeStore interface {
+	// SaveCode stores the grant until expiresAt.
+	SaveCode(ctx context.Context, code string, grant Grant, expiresAt time.Time) error
+	// TakeCode returns and deletes the grant, so every code can be redeemed once. Returns ErrCodeNotFound
+	// for unknown, expired and already redeemed codes.
+	TakeCode(ctx context.Context, code string) (*Grant, error)
+}
+
+// MemoryStore is an in-memory ClientStore and CodeStore for tests and demos. Its codes are lost on restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	clients map[string]Client
+	codes   map[string]memoryGrant
+}
+
+type memoryGrant struct {
+	grant     Grant
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a MemoryStore with the clients.
+func NewMemoryStore(clients ...Client) *MemoryStore {
+	s := &MemoryStore{
+		clients: make(map[string]Client),
+		codes:   make(map[string]memoryGrant),
+	}
+	for _, client := range clients {
+		s.clients[client.ID] = client
+	}
+	return s
+}
+
+// AddClient registers or replaces a client.
+func (s *MemoryStore) AddClient(client Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[client.ID] = client
+}
+
+func (s *MemoryStore) ClientByID(_ context.Context, id string) (*Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[id]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	return &client, nil
+}
+
+func (s *MemoryStore) SaveCode(_ context.Context, code string, grant Grant, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Drop expired codes, unredeemed ones would pile up otherwise
+	now := time.Now()
+	for c, stored := range s.codes {
+		if now.After(stored.expiresAt) {
+			delete(s.codes, c)
+		}
+	}
+	s.codes[code] = memoryGrant{grant: grant, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *MemoryStore) TakeCode(_ context.Context, code string) (*Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.codes[code]
+	if !ok {
+		return nil, ErrCodeNotFound
+	}
+	delete(s.codes, code)
+	if time.Now().After(stored.expiresAt) {
+		return nil, ErrCodeNotFound
+	}
+	return &stored.grant, nil
+}
@@ -0,0 +1,187 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+	"github.com/MrBoombastic/WebAuthn2Go/jwt"
+)
+
+// IDTokenClaims are the claims of ID tokens.
+type IDTokenClaims struct {
+	jwt.Claims
+	Nonce             string `json:"nonce,omitempty"`
+	AuthorizedParty   string `json:"azp,omitempty"`
+	Name              string `json:"name,omitempty"`               // With the profile scope
+	PreferredUsername string `json:"preferred_username,omitempty"` // With the profile scope
+}
+
+// accessTokenClaims are the claims of access tokens (RFC 9068).
+type accessTokenClaims struct {
+	jwt.Claims
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// TokenResponse is the response of the token endpoint.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// token handles token requests, only the authorization_code grant is supported.
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		p.writeError(w, r, &Error{Status: http.StatusMethodNotAllowed, Code: CodeInvalidRequest, Description: "Method not allowed"})
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxTokenRequestBytes)
+	if err := r.ParseForm(); err != nil {
+		p.writeError(w, r, invalidRequest("Failed to parse request body", err))
+		return
+	}
+	response, err := p.exchangeCode(r)
+	if err != nil {
+		var oauthErr *Error
+		if !errors.As(err, &oauthErr) {
+			oauthErr = serverError("Internal error", err)
+		}
+		if oauthErr.Status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		}
+		p.writeError(w, r, oauthErr)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, response)
+}
+
+// exchangeCode authenticates the client and redeems the authorization code.
+func (p *Provider) exchangeCode(r *http.Request) (*TokenResponse, error) {
+	clientID, clientSecret, basic := r.BasicAuth()
+	if basic {
+		// Basic credentials are form-urlencoded first (RFC 6749 section 2.3.1)
+		var errID, errSecret error
+		clientID, errID = url.QueryUnescape(clientID)
+		clientSecret, errSecret = url.QueryUnescape(clientSecret)
+		if errID != nil || errSecret != nil {
+			return nil, invalidClient(nil)
+		}
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	client, err := p.config.Clients.ClientByID(r.Context(), clientID)
+	if errors.Is(err, ErrClientNotFound) {
+		return nil, invalidClient(err)
+	} else if err != nil {
+		return nil, serverError("Failed to look up client", err)
+	}
+	if client.Secret != "" && subtle.ConstantTimeCompare([]byte(clientSecret), []byte(client.Secret)) == 0 {
+		return nil, invalidClient(nil)
+	}
+
+	if grantType := r.PostForm.Get("grant_type"); grantType != "authorization_code" {
+		return nil, &Error{Status: http.StatusBadRequest, Code: CodeUnsupportedGrantType, Description: "Unsupported grant type " + grantType}
+	}
+	// Take the code first, so it can't be redeemed twice even if this request fails
+	grant, err := p.config.Codes.TakeCode(r.Context(), r.PostForm.Get("code"))
+	if errors.Is(err, ErrCodeNotFound) {
+		return nil, invalidGrant("Invalid authorization code", err)
+	} else if err != nil {
+		return nil, serverError("Failed to load code", err)
+	}
+	if grant.ClientID != client.ID {
+		return nil, invalidGrant("Code issued to another client", nil)
+	}
+	if grant.RedirectURI != r.PostForm.Get("redirect_uri") {
+		return nil, invalidGrant("Redirect URI mismatch", nil)
+	}
+	verifier := r.PostForm.Get("code_verifier")
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return nil, invalidGrant("Invalid code verifier", nil)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(challenge[:])), []byte(grant.CodeChallenge)) == 0 {
+		return nil, invalidGrant("Code verifier mismatch", nil)
+	}
+
+	return p.issueTokens(r, grant)
+}
+
+// issueTokens signs the ID and access tokens of the grant.
+func (p *Provider) issueTokens(r *http.Request, grant *Grant) (*TokenResponse, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return nil, serverError("Failed to generate token ID", err)
+	}
+	now := time.Now()
+	base := jwt.Claims{
+		Issuer:    p.config.Issuer,
+		Subject:   base64.RawURLEncoding.EncodeToString(grant.UserID),
+		ExpiresAt: now.Add(p.config.TokenLifetime).Unix(),
+		IssuedAt:  now.Unix(),
+		AuthTime:  grant.AuthTime,
+		AMR:       grant.AMR,
+		ACR:       p.tokens.ACR(grant.loginResult()),
+	}
+
+	idClaims := IDTokenClaims{Claims: base, Nonce: grant.Nonce, AuthorizedParty: grant.ClientID}
+	idClaims.Audience = jwt.Audience{grant.ClientID}
+	if contains(grant.Scopes, ScopeProfile) {
+		user, err := p.config.Ceremonies.Users.UserByID(r.Context(), grant.UserID)
+		if err != nil {
+			return nil, serverError("Failed to look up user", err)
+		}
+		idClaims.Name, idClaims.PreferredUsername = user.DisplayName, user.Name
+	}
+	idToken, err := p.tokens.Sign(idClaims)
+	if err != nil {
+		return nil, serverError("Failed to sign ID token", err)
+	}
+
+	scope := strings.Join(grant.Scopes, " ")
+	accessClaims := accessTokenClaims{Claims: base, ClientID: grant.ClientID, Scope: scope}
+	accessClaims.Audience = jwt.Audience{p.config.Issuer}
+	accessClaims.ID = base64.RawURLEncoding.EncodeToString(jti)
+	accessToken, err := p.tokens.Sign(accessClaims)
+	if err != nil {
+		return nil, serverError("Failed to sign access token", err)
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(p.config.TokenLifetime / time.Second),
+		IDToken:     idToken,
+		Scope:       scope,
+	}, nil
+}
+
+// loginResult rebuilds the parts of the passkey login the session kept, for deriving the acr claim.
+func (g *Grant) loginResult() *webauthn.LoginResult {
+	return &webauthn.LoginResult{
+		UserID:         g.UserID,
+		CredentialID:   g.CredentialID,
+		UserVerified:   g.UserVerified,
+		BackupEligible: contains(g.AMR, webauthn.AMRSoftwareKey),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
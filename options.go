@@ -0,0 +1,152 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/MrBoombastic/WebAuthn2Go/cose"
+)
+
+// Ceremony identifies the WebAuthn ceremony an option or extension takes part in.
+type Ceremony string
+
+const (
+	CeremonyRegistration   Ceremony = "registration"
+	CeremonyAuthentication Ceremony = "authentication"
+)
+
+// Option customizes the options generated by BeginRegistration, BeginLogin and their variants.
+// Options not applicable to the ceremony make it fail with ErrOptionNotApplicable.
+type Option func(c *CeremonyOptions) error
+
+// CeremonyOptions gives options and extensions access to the options being generated.
+// Exactly one of Registration and Login is set, depending on Ceremony.
+type CeremonyOptions struct {
+	Ceremony     Ceremony
+	Registration *BeginRegistrationOptions
+	Login        *PublicKeyCredentialRequestOptions
+	// transportHints is set by WithCredentialTransports, so BeginLoginForUser can add hints to fake credentials
+	transportHints bool
+}
+
+// descriptors returns the allow list of a login, or the exclude list of a registration.
+func (c *CeremonyOptions) descriptors() []PublicKeyCredentialDescriptor {
+	if c.Ceremony == CeremonyRegistration {
+		return c.Registration.ExcludeCredentials
+	}
+	return c.Login.AllowCredentials
+}
+
+// SetExtensionInput sets the client extension input with the identifier, replacing a previous one.
+func (c *CeremonyOptions) SetExtensionInput(identifier string, input any) error {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", ErrFailedEncodeExtensionInput, identifier, err)
+	}
+	inputs := c.extensionInputs()
+	if *inputs == nil {
+		*inputs = make(ExtensionInputs)
+	}
+	(*inputs)[identifier] = raw
+	return nil
+}
+
+// extensionInputs returns the extension inputs of the options being generated.
+func (c *CeremonyOptions) extensionInputs() *ExtensionInputs {
+	if c.Ceremony == CeremonyRegistration {
+		return &c.Registration.Extensions
+	}
+	return &c.Login.Extensions
+}
+
+// WithExtension requests the extension, see Extension.
+func WithExtension(ext Extension) Option {
+	return func(c *CeremonyOptions) error {
+		return ext.AddInput(c)
+	}
+}
+
+// WithResidentKey requests a discoverable credential (passkey), or discourages one. Registration only.
+func WithResidentKey(requirement ResidentKeyRequirement) Option {
+	return func(c *CeremonyOptions) error {
+		if c.Ceremony != CeremonyRegistration {
+			return fmt.Errorf("%w: residentKey", ErrOptionNotApplicable)
+		}
+		if !requirement.IsValid() {
+			return fmt.Errorf("%w: %q", ErrInvalidResidentKey, requirement)
+		}
+		c.Registration.AuthenticatorSelection.ResidentKey = requirement
+		c.Registration.AuthenticatorSelection.RequireResidentKey = requirement == ResidentKeyRequired
+		return nil
+	}
+}
+
+// WithAuthenticatorAttachment restricts the registration to platform or roaming authenticators. Registration only.
+func WithAuthenticatorAttachment(attachment AuthenticatorAttachment) Option {
+	return func(c *CeremonyOptions) error {
+		if c.Ceremony != CeremonyRegistration {
+			return fmt.Errorf("%w: authenticatorAttachment", ErrOptionNotApplicable)
+		}
+		if !attachment.IsValid() {
+			return fmt.Errorf("%w: %q", ErrInvalidAuthenticatorAttachment, attachment)
+		}
+		c.Registration.AuthenticatorSelection.AuthenticatorAttachment = attachment
+		return nil
+	}
+}
+
+// WithAlgorithms offers these algorithms instead of Config.Algorithms, in order of preference. FinishRegistration
+// rejects credentials using other algorithms. Registration only.
+func WithAlgorithms(algorithms ...cose.Algorithm) Option {
+	return func(c *CeremonyOptions) error {
+		if c.Ceremony != CeremonyRegistration {
+			return fmt.Errorf("%w: pubKeyCredParams", ErrOptionNotApplicable)
+		}
+		if len(algorithms) == 0 {
+			return fmt.Errorf("%w: no algorithms", ErrUnsupportedAlgorithm)
+		}
+		if err := validateAlgorithms(algorithms); err != nil {
+			return err
+		}
+		c.Registration.PubKeyCredParams = credentialParameters(algorithms)
+		return nil
+	}
+}
+
+// WithCredentialTransports adds the stored transports (RegistrationResult.Transports) to the allowed
+// credentials of a login, or the excluded credentials of a registration, keyed by base64url encoded credential ID.
+// The client uses them to pick how to reach the authenticator, e.g., not to offer a QR code for a platform passkey.
+// Credentials missing from the list are ignored, so pass it after WithExcludeCredentials.
+func WithCredentialTransports(transports map[string][]AuthenticatorTransport) Option {
+	return func(c *CeremonyOptions) error {
+		descriptors := c.descriptors()
+		for i := range descriptors {
+			if t, ok := transports[descriptors[i].ID]; ok {
+				descriptors[i].Transports = knownTransports(t)
+			}
+		}
+		c.transportHints = true
+		return nil
+	}
+}
+
+// applyRegistrationOptions applies opts to the registration options in order.
+func applyRegistrationOptions(o *BeginRegistrationOptions, opts []Option) error {
+	c := &CeremonyOptions{Ceremony: CeremonyRegistration, Registration: o}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyLoginOptions applies opts to the login options in order.
+func applyLoginOptions(o *PublicKeyCredentialRequestOptions, opts []Option) error {
+	c := &CeremonyOptions{Ceremony: CeremonyAuthentication, Login: o}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
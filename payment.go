@@ -0,0 +1,293 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	paymentIdentifier = "payment"
+	// CTAP extension marking credentials usable for payments by third parties
+	thirdPartyPaymentIdentifier = "thirdPartyPayment"
+	// PaymentMethodSPC is the PaymentRequest method identifier of Secure Payment Confirmation.
+	PaymentMethodSPC = "secure-payment-confirmation"
+)
+
+var (
+	// Currency codes and amounts as validated by the Payment Request API
+	paymentCurrencyPattern = regexp.MustCompile(`^[a-zA-Z]{3}$`)
+	paymentValuePattern    = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+)
+
+// PaymentCurrencyAmount is a monetary amount, e.g., {"currency": "EUR", "value": "12.34"}.
+type PaymentCurrencyAmount struct {
+	Currency string `json:"currency"`
+	Value    string `json:"value"`
+}
+
+// PaymentCredentialInstrument is the payment instrument shown to the user, e.g., a card.
+type PaymentCredentialInstrument struct {
+	DisplayName     string `json:"displayName"`
+	Icon            string `json:"icon"` // URL of the instrument icon
+	IconMustBeShown bool   `json:"iconMustBeShown,omitempty"`
+}
+
+// PaymentDetails describes the transaction the user confirms. At least one of PayeeName and PayeeOrigin is required.
+type PaymentDetails struct {
+	Instrument  PaymentCredentialInstrument
+	PayeeName   string
+	PayeeOrigin string // Origin of the merchant, also allowed to call SPC on behalf of the RP
+	Total       PaymentCurrencyAmount
+}
+
+// SecurePaymentConfirmationRequest is the data of the "secure-payment-confirmation" payment method.
+// Challenge and CredentialIDs are base64url encoded and must be decoded to BufferSources by the client.
+type SecurePaymentConfirmationRequest struct {
+	Challenge     string                      `json:"challenge"`
+	RPID          string                      `json:"rpId"`
+	CredentialIDs []string                    `json:"credentialIds"`
+	Instrument    PaymentCredentialInstrument `json:"instrument"`
+	PayeeName     string                      `json:"payeeName,omitempty"`
+	PayeeOrigin   string                      `json:"payeeOrigin,omitempty"`
+	Timeout       uint32                      `json:"timeout,omitempty"`
+	Extensions    ExtensionInputs             `json:"extensions,omitempty"`
+}
+
+// PaymentConfirmationOptions holds the arguments for new PaymentRequest():
+// [{supportedMethods, data}] as the method data and {total: {label, amount: total}} as the details.
+type PaymentConfirmationOptions struct {
+	SupportedMethods string                           `json:"supportedMethods"` // Always PaymentMethodSPC
+	Data             SecurePaymentConfirmationRequest `json:"data"`
+	Total            PaymentCurrencyAmount            `json:"total"`
+}
+
+// CollectedPaymentData is the "payment" member of the client data of a payment.get assertion,
+// that is the transaction the browser displayed to the user.
+type CollectedPaymentData struct {
+	RPID        string                      `json:"rpId"`
+	TopOrigin   string                      `json:"topOrigin"` // Origin of the checkout page
+	PayeeName   string                      `json:"payeeName,omitempty"`
+	PayeeOrigin string                      `json:"payeeOrigin,omitempty"`
+	Total       PaymentCurrencyAmount       `json:"total"`
+	Instrument  PaymentCredentialInstrument `json:"instrument"`
+}
+
+// PaymentConfirmationResult holds the successful result of a Secure Payment Confirmation ceremony.
+type PaymentConfirmationResult struct {
+	LoginResult
+	Payment CollectedPaymentData `json:"payment"` // Transaction confirmed by the user
+}
+
+// PaymentExtensionInputs is the client input of the payment extension.
+type PaymentExtensionInputs struct {
+	IsPayment bool `json:"isPayment"`
+}
+
+// PaymentExtension marks a new credential as usable for Secure Payment Confirmation, see WithPaymentCredential.
+// The browser adds the authentication inputs itself.
+type PaymentExtension struct{}
+
+// WithPaymentCredential requests the payment extension, so the credential can be used with
+// BeginPaymentConfirmation, including by the merchant on behalf of the RP. Registration only.
+func WithPaymentCredential() Option {
+	return WithExtension(PaymentExtension{})
+}
+
+// Identifier returns "payment".
+func (PaymentExtension) Identifier() string { return paymentIdentifier }
+
+// AddInput requests the extension.
+func (PaymentExtension) AddInput(c *CeremonyOptions) error {
+	if c.Ceremony != CeremonyRegistration {
+		return fmt.Errorf("%w: payment", ErrOptionNotApplicable)
+	}
+	return c.SetExtensionInput(paymentIdentifier, PaymentExtensionInputs{IsPayment: true})
+}
+
+// ValidateAuthenticatorOutput takes the thirdPartyPayment output some authenticators report.
+func (PaymentExtension) ValidateAuthenticatorOutput(_ Ceremony, outputs *AuthenticatorExtensionOutputs, _ *Extensions) error {
+	outputs.Take(thirdPartyPaymentIdentifier)
+	return nil
+}
+
+// ValidateClientOutput does nothing, payment has no client output.
+func (PaymentExtension) ValidateClientOutput(Ceremony, json.RawMessage, *Extensions) error {
+	return nil
+}
+
+func decodePaymentExtension(ceremony Ceremony, inputs ExtensionInputs) (Extension, error) {
+	var in PaymentExtensionInputs
+	if ok, err := decodeExtensionInput(inputs, paymentIdentifier, &in); !ok || !in.IsPayment || ceremony != CeremonyRegistration {
+		return nil, err
+	}
+	return PaymentExtension{}, nil
+}
+
+// BeginPaymentConfirmation generates options for a Secure Payment Confirmation ceremony, in which the browser
+// displays the transaction and the user confirms it with one of the credentials (registered WithPaymentCredential).
+// Options (e.g., WithPRF) can request additional extensions.
+func (w *WebAuthn) BeginPaymentConfirmation(payment PaymentDetails, credentialIDs []string, opts ...Option) (*PaymentConfirmationOptions, error) {
+	if w == nil {
+		return nil, ErrNilInstance
+	}
+	if err := payment.validate(); err != nil {
+		return nil, err
+	}
+	if len(credentialIDs) == 0 {
+		return nil, fmt.Errorf("%w: no credential IDs", ErrInvalidPaymentDetails)
+	}
+	login, err := w.BeginLogin(credentialIDs, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentConfirmationOptions{
+		SupportedMethods: PaymentMethodSPC,
+		Data: SecurePaymentConfirmationRequest{
+			Challenge:     login.Challenge,
+			RPID:          login.RPID,
+			CredentialIDs: credentialIDs,
+			Instrument:    payment.Instrument,
+			PayeeName:     payment.PayeeName,
+			PayeeOrigin:   payment.PayeeOrigin,
+			Timeout:       login.Timeout,
+			Extensions:    login.Extensions,
+		},
+		Total: payment.Total,
+	}, nil
+}
+
+// FinishPaymentConfirmation verifies a payment.get assertion against the options returned by BeginPaymentConfirmation.
+// It succeeds only if the transaction displayed by the browser matches the requested one and the user was verified.
+// The assertion may come from the RP origins or from the payee origin.
+func (w *WebAuthn) FinishPaymentConfirmation(data *LoginData, options *PaymentConfirmationOptions) (*PaymentConfirmationResult, error) {
+	if w == nil {
+		return nil, ErrNilInstance
+	}
+	if data == nil {
+		return nil, ErrNilLoginData
+	}
+	if options == nil {
+		return nil, ErrMissingPaymentOptions
+	}
+
+	// Validate against the request options the payment options were built from
+	c := *data
+	c.Options = &PublicKeyCredentialRequestOptions{
+		Challenge:  options.Data.Challenge,
+		RPID:       options.Data.RPID,
+		Extensions: options.Data.Extensions,
+	}
+	var collected CollectedPaymentData
+	res, err := w.validateAssertion(&c, assertionCheck{
+		clientDataType: "payment.get",
+		typeErr:        ErrTypeNotPaymentGet,
+		isAllowedOrigin: func(origin string) (bool, error) {
+			if options.Data.PayeeOrigin != "" && sameOrigin(origin, options.Data.PayeeOrigin) {
+				return true, nil
+			}
+			return w.isAllowedOrigin(origin)
+		},
+		checkClientData: func(clientDataJSON []byte) (err error) {
+			collected, err = options.checkCollectedPaymentData(clientDataJSON)
+			return err
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("assertion validation failed: %w", err)
+	}
+	if !res.UserVerified {
+		return nil, ErrUserVerifiedFlagNotSet
+	}
+
+	return &PaymentConfirmationResult{
+		LoginResult: LoginResult{
+			NewSignCount:   res.NewSignCount,
+			UserVerified:   res.UserVerified,
+			RPID:           res.RPID,
+			Extensions:     res.Extensions,
+			BackupEligible: res.BackupEligible,
+			BackedUp:       res.BackedUp,
+			Compliance:     res.Compliance,
+			PRF:            res.Extensions.PRF,
+			LargeBlob:      res.Extensions.LargeBlob,
+		},
+		Payment: collected,
+	}, nil
+}
+
+// checkCollectedPaymentData checks that the transaction in the client data is the requested one.
+func (o *PaymentConfirmationOptions) checkCollectedPaymentData(clientDataJSON []byte) (CollectedPaymentData, error) {
+	var clientData struct {
+		Payment *CollectedPaymentData `json:"payment"`
+	}
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return CollectedPaymentData{}, fmt.Errorf("%w: %w", ErrFailedUnmarshalClientData, err)
+	}
+	p := clientData.Payment
+	if p == nil {
+		return CollectedPaymentData{}, fmt.Errorf("%w: missing payment data", ErrPaymentMismatch)
+	}
+
+	var mismatch string
+	switch {
+	case p.RPID != o.Data.RPID:
+		mismatch = "rpId"
+	case p.TopOrigin == "":
+		mismatch = "topOrigin"
+	case p.PayeeName != o.Data.PayeeName:
+		mismatch = "payeeName"
+	case !sameOrigin(p.PayeeOrigin, o.Data.PayeeOrigin):
+		mismatch = "payeeOrigin"
+	case !strings.EqualFold(p.Total.Currency, o.Total.Currency) || p.Total.Value != o.Total.Value:
+		mismatch = "total"
+	case p.Instrument.DisplayName != o.Data.Instrument.DisplayName:
+		mismatch = "instrument.displayName"
+	case p.Instrument.Icon != o.Data.Instrument.Icon:
+		mismatch = "instrument.icon"
+	}
+	if mismatch != "" {
+		return CollectedPaymentData{}, fmt.Errorf("%w: %s", ErrPaymentMismatch, mismatch)
+	}
+	return *p, nil
+}
+
+// validate checks the payment details the way the Payment Request API does.
+func (p PaymentDetails) validate() error {
+	if p.Instrument.DisplayName == "" || p.Instrument.Icon == "" {
+		return fmt.Errorf("%w: instrument needs a display name and an icon", ErrInvalidPaymentDetails)
+	}
+	if p.PayeeName == "" && p.PayeeOrigin == "" {
+		return fmt.Errorf("%w: payee name or origin is required", ErrInvalidPaymentDetails)
+	}
+	if p.PayeeOrigin != "" {
+		u, err := url.Parse(p.PayeeOrigin)
+		if err != nil || u.Scheme != "https" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("%w: payee origin %s must be an HTTPS origin", ErrInvalidPaymentDetails, p.PayeeOrigin)
+		}
+	}
+	if !paymentCurrencyPattern.MatchString(p.Total.Currency) || !paymentValuePattern.MatchString(p.Total.Value) {
+		return fmt.Errorf("%w: invalid total %s %s", ErrInvalidPaymentDetails, p.Total.Value, p.Total.Currency)
+	}
+	return nil
+}
+
+// sameOrigin compares two origins by scheme and host, ignoring case and default ports like isAllowedOrigin.
+// Two empty origins are the same, an empty or unparsable one never matches any other.
+func sameOrigin(a, b string) bool {
+	if a == "" || b == "" {
+		return a == b
+	}
+	ua, err := url.Parse(a)
+	if err != nil || ua.Scheme == "" || ua.Host == "" {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil || ub.Scheme == "" || ub.Host == "" {
+		return false
+	}
+	return newParsedOrigin(ua) == newParsedOrigin(ub)
+}
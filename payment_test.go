@@ -0,0 +1,34 @@
+package webauthn
+
+import "testing"
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"https://merchant.example", "https://merchant.example", true},
+		{"https://Merchant.Example", "HTTPS://merchant.example", true},
+		{"https://merchant.example/", "https://merchant.example", true},
+		{"https://merchant.example:443", "https://merchant.example", true},
+		{"http://merchant.example:80", "http://merchant.example", true},
+		{"https://[::1]:443", "https://[::1]", true},
+		{"", "", true},
+		{"https://merchant.example:8443", "https://merchant.example", false},
+		{"http://merchant.example:443", "http://merchant.example", false},
+		{"http://merchant.example", "https://merchant.example", false},
+		{"https://merchant.example", "https://merchant.example.evil", false},
+		{"https://merchant.example", "https://shop.merchant.example", false},
+		{"https://merchant.example", "", false},
+		{"merchant.example", "merchant.example", false},
+		{"https://merchant.example%", "https://merchant.example%", false},
+	}
+	for _, tt := range tests {
+		if got := sameOrigin(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameOrigin(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := sameOrigin(tt.b, tt.a); got != tt.want {
+			t.Errorf("sameOrigin(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package webauthn
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+// prfSaltPrefix is prepended to PRF inputs by the client before they are sent to the authenticator.
+const prfSaltPrefix = "WebAuthn PRF"
+
+// prfOutputLength is the length of every PRF result (bytes).
+const prfOutputLength = 32
+
+// PRFValues holds one or two PRF inputs, or the matching results.
+type PRFValues struct {
+	First  Base64URL `json:"first"`
+	Second Base64URL `json:"second,omitempty"`
+}
+
+// PRFInputs is the client input of the prf extension.
+type PRFInputs struct {
+	Eval *PRFValues `json:"eval,omitempty"`
+	// EvalByCredential holds inputs per base64url encoded credential ID. Login only,
+	// and every ID must be in the allow list.
+	EvalByCredential map[string]PRFValues `json:"evalByCredential,omitempty"`
+}
+
+// PRFOutputs is the client output of the prf extension.
+type PRFOutputs struct {
+	Enabled *bool      `json:"enabled,omitempty"` // Registration only, whether the credential supports PRF
+	Results *PRFValues `json:"results,omitempty"` // Secrets derived from the inputs, if evaluated
+}
+
+// PRFValuesFromContext builds PRF inputs from context strings, e.g., "vault-key-v1".
+// Use a different context for every secret you derive.
+func PRFValuesFromContext(first string, second ...string) PRFValues {
+	v := PRFValues{First: Base64URL(first)}
+	if len(second) > 0 {
+		v.Second = Base64URL(second[0])
+	}
+	return v
+}
+
+// PRFValuesFromBytes builds PRF inputs from raw bytes.
+func PRFValuesFromBytes(first []byte, second ...[]byte) PRFValues {
+	v := PRFValues{First: first}
+	if len(second) > 0 {
+		v.Second = second[0]
+	}
+	return v
+}
+
+// PRFSalt derives the salt the client sends to the authenticator for a PRF input,
+// that is SHA-256("WebAuthn PRF" || 0x00 || input). Useful when talking to authenticators
+// via CTAP hmac-secret directly, as the browser never exposes the salt.
+func PRFSalt(input []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(prfSaltPrefix))
+	h.Write([]byte{0x00})
+	h.Write(input)
+	return h.Sum(nil)
+}
+
+const (
+	prfIdentifier = "prf"
+	// The client implements prf on top of the CTAP hmac-secret extensions
+	hmacSecretIdentifier   = "hmac-secret"
+	hmacSecretMCIdentifier = "hmac-secret-mc"
+)
+
+// PRFExtension evaluates a pseudo-random function bound to the credential, see WithPRF.
+type PRFExtension struct {
+	Inputs PRFInputs
+}
+
+// WithPRF requests the prf extension. At registration, it asks whether the credential supports PRF
+// and optionally evaluates Eval; at login, it evaluates Eval or EvalByCredential.
+func WithPRF(inputs PRFInputs) Option {
+	return WithExtension(&PRFExtension{Inputs: inputs})
+}
+
+// Identifier returns "prf".
+func (e *PRFExtension) Identifier() string { return prfIdentifier }
+
+// AddInput checks the inputs against the ceremony and adds them.
+func (e *PRFExtension) AddInput(c *CeremonyOptions) error {
+	inputs := e.Inputs
+	if inputs.Eval != nil && len(inputs.Eval.First) == 0 {
+		return fmt.Errorf("%w: eval.first is empty", ErrInvalidPRFInputs)
+	}
+	switch c.Ceremony {
+	case CeremonyRegistration:
+		if len(inputs.EvalByCredential) > 0 {
+			return fmt.Errorf("%w: evalByCredential is not allowed at registration", ErrInvalidPRFInputs)
+		}
+	case CeremonyAuthentication:
+		for credID, values := range inputs.EvalByCredential {
+			if len(values.First) == 0 {
+				return fmt.Errorf("%w: evalByCredential.%s.first is empty", ErrInvalidPRFInputs, credID)
+			}
+			if !hasAllowedCredential(c.Login.AllowCredentials, credID) {
+				return fmt.Errorf("%w: evalByCredential credential %s is not in the allow list", ErrInvalidPRFInputs, credID)
+			}
+		}
+	}
+	return c.SetExtensionInput(prfIdentifier, inputs)
+}
+
+// ValidateAuthenticatorOutput takes the hmac-secret outputs. They are encrypted for the client,
+// which reports the decrypted results as the client output.
+func (e *PRFExtension) ValidateAuthenticatorOutput(_ Ceremony, outputs *AuthenticatorExtensionOutputs, _ *Extensions) error {
+	outputs.Take(hmacSecretIdentifier)
+	outputs.Take(hmacSecretMCIdentifier)
+	return nil
+}
+
+// ValidateClientOutput checks that results were requested and have the expected length.
+func (e *PRFExtension) ValidateClientOutput(ceremony Ceremony, output json.RawMessage, result *Extensions) error {
+	if output == nil {
+		return nil
+	}
+	var out PRFOutputs
+	if err := decodeExtensionOutput(prfIdentifier, output, &out); err != nil {
+		return err
+	}
+	if out.Enabled != nil && ceremony != CeremonyRegistration {
+		return fmt.Errorf("%w: enabled is only reported at registration", ErrInvalidPRFOutputs)
+	}
+	if out.Results != nil {
+		if e.Inputs.Eval == nil && len(e.Inputs.EvalByCredential) == 0 {
+			return fmt.Errorf("%w: results without inputs", ErrInvalidPRFOutputs)
+		}
+		if len(out.Results.First) != prfOutputLength {
+			return fmt.Errorf("%w: first result has %d bytes", ErrInvalidPRFOutputs, len(out.Results.First))
+		}
+		if out.Results.Second != nil && len(out.Results.Second) != prfOutputLength {
+			return fmt.Errorf("%w: second result has %d bytes", ErrInvalidPRFOutputs, len(out.Results.Second))
+		}
+	}
+	result.PRF = &out
+	return nil
+}
+
+func decodePRFExtension(_ Ceremony, inputs ExtensionInputs) (Extension, error) {
+	ext := &PRFExtension{}
+	if ok, err := decodeExtensionInput(inputs, prfIdentifier, &ext.Inputs); !ok {
+		return nil, err
+	}
+	return ext, nil
+}
+
+// hasAllowedCredential reports whether the base64url encoded ID is in the allow list.
+func hasAllowedCredential(allowed []PublicKeyCredentialDescriptor, credID string) bool {
+	for _, c := range allowed {
+		if c.ID == credID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+// Package psl answers public suffix questions using an embedded snapshot of the Public Suffix List
+// (https://publicsuffix.org). It's used to validate RP IDs and to compute registrable domains.
+package psl
+
+import (
+	_ "embed"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// SnapshotDate is the date (ISO 8601) of the embedded public_suffix_list.dat snapshot, upstream version
+// 20230209.2326. The list itself carries no date header.
+const SnapshotDate = "2023-02-09"
+
+//go:embed public_suffix_list.dat
+var listData string
+
+var (
+	ErrEmptyDomain    = errors.New("domain cannot be empty")
+	ErrIsPublicSuffix = errors.New("domain is a public suffix")
+)
+
+type ruleKind uint8
+
+const (
+	ruleNormal ruleKind = iota + 1
+	ruleWildcard
+	ruleException
+)
+
+type rule struct {
+	kind  ruleKind
+	icann bool
+}
+
+var (
+	rulesOnce sync.Once
+	rules     map[string]rule
+)
+
+// loadRules parses the embedded list. Non-ASCII rules are stored in both Unicode and punycode form.
+func loadRules() {
+	rules = make(map[string]rule, 16384)
+	icann := false
+	for _, line := range strings.Split(listData, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "// ===BEGIN ICANN DOMAINS===") {
+			icann = true
+			continue
+		}
+		if strings.HasPrefix(line, "// ===END ICANN DOMAINS===") {
+			icann = false
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		// Rules end at the first whitespace
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+		r := rule{kind: ruleNormal, icann: icann}
+		switch {
+		case strings.HasPrefix(line, "!"):
+			r.kind = ruleException
+			line = line[1:]
+		case strings.HasPrefix(line, "*."):
+			r.kind = ruleWildcard
+			line = line[2:]
+		}
+		line = strings.ToLower(line)
+		rules[line] = r
+		if ascii := toASCII(line); ascii != line {
+			rules[ascii] = r
+		}
+	}
+}
+
+// normalize lowercases the domain and strips a trailing dot.
+func normalize(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+// lookup returns the public suffix of the domain, whether it comes from the ICANN section,
+// and whether an explicit list rule matched (as opposed to the implicit "*" rule).
+func lookup(domain string) (suffix string, icann bool, listed bool) {
+	rulesOnce.Do(loadRules)
+
+	labels := strings.Split(domain, ".")
+	for i := range labels {
+		candidate := strings.Join(labels[i:], ".")
+		r, ok := rules[candidate]
+		if !ok {
+			continue
+		}
+		switch r.kind {
+		case ruleException:
+			// Exception rules remove their leftmost label from the suffix
+			return strings.Join(labels[i+1:], "."), r.icann, true
+		case ruleWildcard:
+			if i > 0 {
+				return strings.Join(labels[i-1:], "."), r.icann, true
+			}
+			// "*.ck" does not match "ck" itself, keep looking
+			continue
+		default:
+			return candidate, r.icann, true
+		}
+	}
+	// Implicit "*" rule - the last label is the suffix
+	return labels[len(labels)-1], false, false
+}
+
+// PublicSuffix returns the public suffix of the domain and whether it is managed by ICANN.
+// If no list rule matches, the last label is returned, as the PSL algorithm prescribes.
+//
+// Example:
+//
+//	suffix, icann := psl.PublicSuffix("login.example.co.uk")
+//	// "co.uk", true
+func PublicSuffix(domain string) (suffix string, icann bool) {
+	suffix, icann, _ = lookup(normalize(domain))
+	return suffix, icann
+}
+
+// IsPublicSuffix reports whether the domain itself is listed as a public suffix (e.g., "com", "co.uk", "github.io").
+// Single-label names without a list entry, like "localhost", are not considered public suffixes.
+func IsPublicSuffix(domain string) bool {
+	domain = normalize(domain)
+	if domain == "" {
+		return false
+	}
+	suffix, _, listed := lookup(domain)
+	return listed && suffix == domain
+}
+
+// EffectiveTLDPlusOne returns the registrable domain, that is the public suffix plus one more label.
+// For example, "login.example.co.uk" gives "example.co.uk".
+func EffectiveTLDPlusOne(domain string) (string, error) {
+	domain = normalize(domain)
+	if domain == "" {
+		return "", ErrEmptyDomain
+	}
+	suffix, _, _ := lookup(domain)
+	if len(domain) <= len(suffix) {
+		return "", ErrIsPublicSuffix
+	}
+	rest := domain[:len(domain)-len(suffix)-1]
+	if i := strings.LastIndexByte(rest, '.'); i >= 0 {
+		rest = rest[i+1:]
+	}
+	return rest + "." + suffix, nil
+}
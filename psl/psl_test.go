@@ -0,0 +1,97 @@
+package psl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPublicSuffix(t *testing.T) {
+	tests := []struct {
+		name      string
+		domain    string
+		want      string
+		wantICANN bool
+	}{
+		{"normal rule", "login.example.co.uk", "co.uk", true},
+		{"single label rule", "example.com", "com", true},
+		{"private rule", "user.github.io", "github.io", false},
+		{"wildcard rule", "foo.bar.ck", "bar.ck", true},
+		{"wildcard rule, suffix only", "bar.ck", "bar.ck", true},
+		{"wildcard rule doesn't match its base", "ck", "ck", false},
+		{"exception rule", "www.ck", "ck", true},
+		{"exception rule, subdomain", "login.www.ck", "ck", true},
+		{"unlisted TLD", "example.notatld", "notatld", false},
+		{"upper case", "Login.Example.CO.UK", "co.uk", true},
+		{"trailing dot", "example.co.uk.", "co.uk", true},
+		{"IDN rule, Unicode", "example.公司.cn", "公司.cn", true},
+		{"IDN rule, punycode", "example.xn--55qx5d.cn", "xn--55qx5d.cn", true},
+		{"IDN rule, punycode TLD", "example.xn--55qx5d.xn--j6w193g", "xn--55qx5d.xn--j6w193g", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, icann := PublicSuffix(tt.domain)
+			if got != tt.want || icann != tt.wantICANN {
+				t.Errorf("PublicSuffix(%q) = %q, %v, want %q, %v", tt.domain, got, icann, tt.want, tt.wantICANN)
+			}
+		})
+	}
+}
+
+func TestIsPublicSuffix(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"com", true},
+		{"co.uk", true},
+		{"CO.UK.", true},
+		{"github.io", true},
+		{"bar.ck", true},
+		{"ck", false},
+		{"www.ck", false},
+		{"xn--55qx5d.cn", true},
+		{"example.com", false},
+		{"localhost", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicSuffix(tt.domain); got != tt.want {
+			t.Errorf("IsPublicSuffix(%q) = %v, want %v", tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestEffectiveTLDPlusOne(t *testing.T) {
+	tests := []struct {
+		name    string
+		domain  string
+		want    string
+		wantErr error
+	}{
+		{"subdomain", "login.example.co.uk", "example.co.uk", nil},
+		{"registrable domain", "example.com", "example.com", nil},
+		{"private rule", "a.b.user.github.io", "user.github.io", nil},
+		{"wildcard rule", "a.foo.bar.ck", "foo.bar.ck", nil},
+		{"exception rule", "a.www.ck", "www.ck", nil},
+		{"IDN rule", "login.example.xn--55qx5d.cn", "example.xn--55qx5d.cn", nil},
+		{"trailing dot", "login.example.com.", "example.com", nil},
+		{"upper case", "LOGIN.Example.COM", "example.com", nil},
+		{"bare TLD", "com", "", ErrIsPublicSuffix},
+		{"bare TLD with trailing dot", "com.", "", ErrIsPublicSuffix},
+		{"public suffix", "co.uk", "", ErrIsPublicSuffix},
+		{"wildcard suffix", "bar.ck", "", ErrIsPublicSuffix},
+		{"empty", "", "", ErrEmptyDomain},
+		{"only a dot", ".", "", ErrEmptyDomain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EffectiveTLDPlusOne(tt.domain)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EffectiveTLDPlusOne(%q) error = %v, want %v", tt.domain, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EffectiveTLDPlusOne(%q) = %q, want %q", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
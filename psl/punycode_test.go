@@ -0,0 +1,52 @@
+package psl
+
+import "testing"
+
+// Sample strings from RFC 3492, section 7.1. The RFC mixes case in some outputs to annotate the input; the encoder
+// emits lower case digits and keeps basic code points as they are.
+func TestPunycodeEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"(B) Chinese (simplified)", "他们为什么不说中文", "ihqwcrb4cv8a8dqg056pqjye"},
+		{"(C) Chinese (traditional)", "他們爲什麽不說中文", "ihqwctvzc91f659drss3x8bo0yb"},
+		{"(D) Czech", "Pročprostěnemluvíčesky", "Proprostnemluvesky-uyb24dma41a"},
+		{"(G) Japanese", "なぜみんな日本語を話してくれないのか", "n8jok5ay5dzabd5bym9f0cm5685rrjetr6pdxa"},
+		{"(I) Russian", "почемужеонинеговорятпорусски", "b1abfaaepdrnnbgefbadotcwatmq2g4l"},
+		{"(J) Spanish", "PorquénopuedensimplementehablarenEspañol", "PorqunopuedensimplementehablarenEspaol-fmd56a"},
+		{"(K) Vietnamese", "TạisaohọkhôngthểchỉnóitiếngViệt", "TisaohkhngthchnitingVit-kjcr8268qyxafd2f1b9g"},
+		{"(L)", "3年B組金八先生", "3B-ww4c5e180e575a65lsy2b"},
+		{"(M)", "安室奈美恵-with-SUPER-MONKEYS", "-with-SUPER-MONKEYS-pc58ag80a8qai00g7n9n"},
+		{"(N)", "Hello-Another-Way-それぞれの場所", "Hello-Another-Way--fc4qua05auwb3674vfr0b"},
+		{"(O)", "ひとつ屋根の下2", "2-u9tlzr9756bt3uc0v"},
+		{"(P)", "MajiでKoiする5秒前", "MajiKoi5-783gue6qz075azm5e"},
+		{"(Q)", "パフィーdeルンバ", "de-jg4avhby1noc0d"},
+		{"(R)", "そのスピードで", "d9juau41awczczp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := punycodeEncode(tt.input); got != tt.want {
+				t.Errorf("punycodeEncode(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToASCII(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"example.com", "example.com"},
+		{"公司.cn", "xn--55qx5d.cn"},
+		{"公司.香港", "xn--55qx5d.xn--j6w193g"},
+		{"bücher.example", "xn--bcher-kva.example"},
+	}
+	for _, tt := range tests {
+		if got := toASCII(tt.domain); got != tt.want {
+			t.Errorf("toASCII(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"github.com/MrBoombastic/WebAuthn2Go/cose"
+	"github.com/MrBoombastic/WebAuthn2Go/utils"
+)
+
+// parseCredentialPublicKey strictly parses the COSE key from the authenticator data of a registration.
+func parseCredentialPublicKey(keyBytes []byte) (cose.PublicKey, error) {
+	key, err := cose.ParsePublicKey(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPublicKey, err)
+	}
+	return key, nil
+}
+
+// parseStoredPublicKey parses a credential public key stored at registration, to verify an assertion. It was validated
+// then, so it's parsed leniently to keep accepting keys stored by earlier versions.
+func parseStoredPublicKey(keyBytes []byte) (cose.PublicKey, error) {
+	key, err := cose.ParsePublicKeyWithOptions(keyBytes, cose.ParseOptions{Lenient: true})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %w", ErrSignatureVerification, ErrInvalidPublicKey, err)
+	}
+	return key, nil
+}
+
+// checkReportedPublicKey checks the base64url SPKI reported by the client against the credential public key.
+func checkReportedPublicKey(reported string, pub crypto.PublicKey) error {
+	der, err := utils.DecodeBase64URL(reported)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublicKeyMismatch, err)
+	}
+	reportedKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublicKeyMismatch, err)
+	}
+	if k, ok := pub.(interface{ Equal(crypto.PublicKey) bool }); !ok || !k.Equal(reportedKey) {
+		return ErrPublicKeyMismatch
+	}
+	return nil
+}
@@ -4,18 +4,39 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"github.com/MrBoombastic/WebAuthn2Go/aaguid"
+	"github.com/MrBoombastic/WebAuthn2Go/cose"
 	"github.com/MrBoombastic/WebAuthn2Go/utils"
 	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
-	"github.com/go-webauthn/webauthn/protocol/webauthncose"
 )
 
-// defaultPubKeyCredParams sets up most common algorithms for public key credential parameters.
-// Also, they are the only ones supported by this lib.
-var defaultPubKeyCredParams = []CredentialParameter{
-	{Type: "public-key", Alg: algES256}, // ES256 (P-256)
-	{Type: "public-key", Alg: algRS256}, // RS256
+// pubKeyCredParams returns the configured algorithms as public key credential parameters.
+// The defaults are narrowed to those allowed by the compliance profile, if any.
+func (w *WebAuthn) pubKeyCredParams() []CredentialParameter {
+	if len(w.Config.Algorithms) > 0 {
+		return credentialParameters(w.Config.Algorithms)
+	}
+	if w.Config.Compliance == nil {
+		return credentialParameters(DefaultAlgorithms)
+	}
+	var allowed []cose.Algorithm
+	for _, alg := range DefaultAlgorithms {
+		if w.Config.Compliance.allows(alg) {
+			allowed = append(allowed, alg)
+		}
+	}
+	return credentialParameters(allowed)
+}
+
+// credentialParameters converts algorithms to public key credential parameters, keeping their order.
+func credentialParameters(algorithms []cose.Algorithm) []CredentialParameter {
+	params := make([]CredentialParameter, 0, len(algorithms))
+	for _, alg := range algorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: int64(alg)})
+	}
+	return params
 }
 
 // BeginRegistration starts the WebAuthn registration process
@@ -23,8 +44,9 @@ var defaultPubKeyCredParams = []CredentialParameter{
 // the challenge encoded as a base64url string.
 // Attestation preference is passed as a parameter.
 // User verification preference is taken from the WebAuthn configuration.
+// Options (e.g., WithPRF) can request additional extensions.
 // FLOW: 1. pass data
-func (w *WebAuthn) BeginRegistration(user UserEntity) (navigator *BeginRegistrationOptions, err error) {
+func (w *WebAuthn) BeginRegistration(user UserEntity, opts ...Option) (navigator *BeginRegistrationOptions, err error) {
 	if w == nil {
 		return nil, ErrNilInstance
 	}
@@ -37,15 +59,23 @@ func (w *WebAuthn) BeginRegistration(user UserEntity) (navigator *BeginRegistrat
 		return nil, fmt.Errorf("%w: %w", ErrGeneratingChallenge, err)
 	}
 	// FLOW 3: return options, done
-	return &BeginRegistrationOptions{
-		Challenge:        challenge,
-		User:             user,
-		PubKeyCredParams: defaultPubKeyCredParams,
-		Timeout:          w.Config.Timeout,
-		Attestation:      w.Config.Attestation,
-		UserVerification: w.Config.UserVerification,
-		RP:               RelyingPartyEntity{ID: w.Config.RPID, Name: w.Config.RPDisplayName},
-	}, nil
+	navigator = &BeginRegistrationOptions{
+		Challenge:              challenge,
+		User:                   user,
+		PubKeyCredParams:       w.pubKeyCredParams(),
+		Timeout:                w.Config.Timeout,
+		Attestation:            w.Config.Attestation,
+		AuthenticatorSelection: AuthenticatorSelection{UserVerification: w.Config.UserVerification},
+		RP:                     RelyingPartyEntity{ID: w.Config.RPID, Name: w.Config.RPDisplayName},
+	}
+	opts = append([]Option{WithExtension(CredPropsExtension{})}, opts...)
+	if err := applyRegistrationOptions(navigator, opts); err != nil {
+		return nil, err
+	}
+	if err := w.checkOfferedAlgorithms(navigator.PubKeyCredParams); err != nil {
+		return nil, err
+	}
+	return navigator, nil
 }
 
 // FinishRegistration completes the WebAuthn registration process
@@ -70,6 +100,10 @@ func (w *WebAuthn) FinishRegistration(data RegistrationData) (*RegistrationResul
 		return nil, err
 	}
 
+	if data.Options != nil && subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(data.Options.Challenge)) == 0 {
+		return nil, ErrChallengeMismatch
+	}
+
 	// FLOW 4: CBOR decode attestation
 	var attObj attestationObject
 	decodedAttestationObject, err := base64.RawURLEncoding.DecodeString(data.AttestationObject)
@@ -85,6 +119,9 @@ func (w *WebAuthn) FinishRegistration(data RegistrationData) (*RegistrationResul
 	if !receivedFmt.IsValid() {                      // Use IsValid method on the enum type
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAttestationFormat, receivedFmt)
 	}
+	if err := w.checkAttestationStatement(attObj.AttStmt); err != nil {
+		return nil, err
+	}
 
 	// Parse authenticator data (contains AAGUID needed for name lookup)
 	authData, err := w.ParseAuthenticatorData(attObj.AuthData)
@@ -114,10 +151,40 @@ func (w *WebAuthn) FinishRegistration(data RegistrationData) (*RegistrationResul
 	if authData.CredentialPubKeyBytes == nil {
 		return nil, ErrMissingPublicKey
 	} // Check if the public key is valid
-	if _, err := webauthncose.ParsePublicKey(authData.CredentialPubKeyBytes); err != nil {
+	key, err := parseCredentialPublicKey(authData.CredentialPubKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkKeyCompliance(key); err != nil {
+		return nil, err
+	}
+	alg := int64(key.Algorithm())
+	spki, err := cose.MarshalPKIX(key)
+	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrInvalidPublicKey, err)
 	}
 
+	// The optional members reported by the client must agree with the authenticator data
+	if data.PublicKeyAlgorithm != nil && *data.PublicKeyAlgorithm != alg {
+		return nil, fmt.Errorf("%w: reported %d, key has %d", ErrAlgorithmMismatch, *data.PublicKeyAlgorithm, alg)
+	}
+	// The authenticator must pick one of the algorithms offered for this ceremony
+	offered := w.pubKeyCredParams()
+	if data.Options != nil {
+		offered = data.Options.PubKeyCredParams
+	}
+	if !algorithmOffered(offered, alg) {
+		return nil, fmt.Errorf("%w: %s", ErrAlgorithmNotOffered, key.Algorithm())
+	}
+	if data.PublicKeySPKI != "" {
+		if err := checkReportedPublicKey(data.PublicKeySPKI, key.CryptoPublicKey()); err != nil {
+			return nil, err
+		}
+	}
+	if data.AuthenticatorAttachment != "" && !data.AuthenticatorAttachment.IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAuthenticatorAttachment, data.AuthenticatorAttachment)
+	}
+
 	// CredentialID must also be present for registration.
 	if authData.CredentialID == nil {
 		return nil, ErrMissingCredentialID
@@ -127,11 +194,52 @@ func (w *WebAuthn) FinishRegistration(data RegistrationData) (*RegistrationResul
 	credIDStr := base64.RawURLEncoding.EncodeToString(authData.CredentialID)
 	name := aaguid.LookupAuthenticatorUUID(authData.AAGUID)
 
+	// Validate extension outputs against the requested extensions.
+	// Without the options, only credProps (always requested by BeginRegistration) is validated, other outputs are ignored.
+	inputs := ExtensionInputs{credPropsIdentifier: json.RawMessage("true")}
+	if data.Options != nil {
+		inputs = data.Options.Extensions
+	}
+	exts, err := w.decodeExtensions(CeremonyRegistration, inputs)
+	if err != nil {
+		return nil, err
+	}
+	extensions, err := validateExtensions(CeremonyRegistration, exts, data.ClientExtensionResults, authData.Extensions, data.Options != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Discoverability is only known to the client
+	var discoverable *bool
+	if credProps := extensions.CredProps; credProps != nil {
+		discoverable = credProps.ResidentKey
+	}
+
 	return &RegistrationResult{
-		CredentialID:      credIDStr, // Return base64url encoded ID
-		PublicKey:         authData.CredentialPubKeyBytes,
-		AAGUID:            authData.AAGUID.String(),
-		AuthenticatorName: name,               // Use the looked-up name (or default)
-		SignCount:         authData.SignCount, // Set the initial sign count from authData
+		CredentialID:            credIDStr, // Return base64url encoded ID
+		PublicKey:               authData.CredentialPubKeyBytes,
+		AAGUID:                  authData.AAGUID.String(),
+		AuthenticatorName:       name,               // Use the looked-up name (or default)
+		SignCount:               authData.SignCount, // Set the initial sign count from authData
+		Discoverable:            discoverable,
+		Extensions:              extensions,
+		Transports:              knownTransports(data.Transports),
+		AuthenticatorAttachment: data.AuthenticatorAttachment,
+		PublicKeyAlgorithm:      alg,
+		PublicKeySPKI:           spki,
+		Compliance:              w.complianceName(),
+		PRF:                     extensions.PRF,
+		LargeBlob:               extensions.LargeBlob,
+		CredProtect:             extensions.CredProtect,
 	}, nil
 }
+
+// algorithmOffered checks if alg is one of the offered public key credential parameters.
+func algorithmOffered(offered []CredentialParameter, alg int64) bool {
+	for _, param := range offered {
+		if param.Type == "public-key" && param.Alg == alg {
+			return true
+		}
+	}
+	return false
+}
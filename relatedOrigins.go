@@ -0,0 +1,78 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/MrBoombastic/WebAuthn2Go/psl"
+	"net/url"
+	"strings"
+)
+
+// WellKnownPath is where the Related Origin Requests document must be served, on the RP ID domain over HTTPS.
+const WellKnownPath = "/.well-known/webauthn"
+
+// maxRelatedOriginLabels is the number of distinct registrable-domain labels browsers process
+// in a well-known document. Origins with further labels are ignored.
+const maxRelatedOriginLabels = 5
+
+// RelatedOriginsDocument is the JSON body of the /.well-known/webauthn document.
+type RelatedOriginsDocument struct {
+	Origins []string `json:"origins"`
+}
+
+// RelatedOriginsDocument builds the well-known document from RPOrigins and RelatedOrigins.
+// It fails if the origins span more registrable-domain labels than browsers accept,
+// e.g., "example.com", "example.co.uk" and "example.de" all share the single label "example".
+func (w *WebAuthn) RelatedOriginsDocument() (*RelatedOriginsDocument, error) {
+	if w == nil {
+		return nil, ErrNilInstance
+	}
+	if w.Config == nil {
+		return nil, ErrNilConfig
+	}
+
+	doc := &RelatedOriginsDocument{Origins: make([]string, 0, len(w.Config.RPOrigins)+len(w.Config.RelatedOrigins))}
+	seenOrigins := make(map[string]bool)
+	labels := make(map[string]bool)
+	for _, originStr := range append(append([]string{}, w.Config.RPOrigins...), w.Config.RelatedOrigins...) {
+		u, err := url.Parse(originStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w %s: %w", ErrInvalidRPOrigin, originStr, err)
+		}
+		origin := strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+		if seenOrigins[origin] {
+			continue
+		}
+		seenOrigins[origin] = true
+		doc.Origins = append(doc.Origins, origin)
+
+		// Browsers skip origins without a registrable domain (e.g., localhost) when counting labels
+		if label := registrableLabel(u.Hostname()); label != "" {
+			labels[label] = true
+		}
+	}
+	if len(labels) > maxRelatedOriginLabels {
+		return nil, fmt.Errorf("%w: got %d, maximum is %d", ErrTooManyRelatedOriginLabels, len(labels), maxRelatedOriginLabels)
+	}
+	return doc, nil
+}
+
+// RelatedOriginsJSON returns the well-known document encoded as JSON, ready to be served at WellKnownPath.
+func (w *WebAuthn) RelatedOriginsJSON() ([]byte, error) {
+	doc, err := w.RelatedOriginsDocument()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// registrableLabel returns the label directly preceding the public suffix,
+// e.g., "example" for "login.example.co.uk". Empty if the host has no registrable domain.
+func registrableLabel(host string) string {
+	registrable, err := psl.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return ""
+	}
+	label, _, _ := strings.Cut(registrable, ".")
+	return label
+}
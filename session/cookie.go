@@ -0,0 +1,50 @@
+package session
+
+import (
+	"net/http"
+	"time"
+)
+
+// defaultCookieName is the session cookie name if Config.CookieName is not set.
+const defaultCookieName = "webauthn_session"
+
+// CookieName returns the name of the session cookie, for frameworks not built on net/http.
+func (m *Manager) CookieName() string {
+	return m.config.CookieName
+}
+
+// SetCookie sets the token as an HttpOnly, Secure, SameSite=Lax cookie expiring with the claims.
+func (m *Manager) SetCookie(w http.ResponseWriter, token string, claims *Claims) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    token,
+		Path:     m.config.CookiePath,
+		Expires:  time.Unix(claims.ExpiresAt, 0),
+		Secure:   !m.config.InsecureCookie,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// FromRequest verifies the session cookie of the request and returns its claims.
+func (m *Manager) FromRequest(r *http.Request) (*Claims, error) {
+	cookie, err := r.Cookie(m.config.CookieName)
+	if err != nil {
+		return nil, ErrMissingCookie
+	}
+	return m.Verify(r.Context(), cookie.Value)
+}
+
+// ClearCookie deletes the session cookie, e.g., on logout. Revoke the token through Config.IsRevoked
+// too if it must stop working before it expires.
+func (m *Manager) ClearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    "",
+		Path:     m.config.CookiePath,
+		MaxAge:   -1,
+		Secure:   !m.config.InsecureCookie,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
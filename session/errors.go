@@ -0,0 +1,19 @@
+package session
+
+import "errors"
+
+var (
+	ErrNoKeys            = errors.New("at least one signing key is required")
+	ErrKeyTooShort       = errors.New("signing key must be at least 32 bytes")
+	ErrInvalidKeyID      = errors.New("key ID must be non-empty and must not contain '.'")
+	ErrDuplicateKeyID    = errors.New("duplicate key ID")
+	ErrNilLoginResult    = errors.New("login result cannot be nil")
+	ErrMissingUserID     = errors.New("login result has no user ID")
+	ErrMalformedToken    = errors.New("malformed session token")
+	ErrUnknownKeyID      = errors.New("session token signed with an unknown key")
+	ErrInvalidSignature  = errors.New("invalid session token signature")
+	ErrExpired           = errors.New("session token expired")
+	ErrRevoked           = errors.New("session token revoked")
+	ErrMissingCookie     = errors.New("session cookie not found")
+	ErrFailedRevokeCheck = errors.New("failed to check session revocation")
+)
@@ -0,0 +1,192 @@
+// Package session mints compact signed session tokens after a successful WebAuthn login.
+//
+// A token is "<kid>.<claims>.<mac>": the ID of the signing key, the base64url JSON claims and their base64url
+// HMAC-SHA256. Tokens are verified with the key named by kid, so keys can be rotated by adding a new key first
+// in Config.Keys and dropping the old one once its tokens have expired.
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+)
+
+const (
+	// minKeyLength is the minimum length of a signing key (bytes).
+	minKeyLength = 32
+	// defaultTTL is the session lifetime if Config.TTL is not set.
+	defaultTTL = 12 * time.Hour
+	// tokenContext separates session token MACs from other uses of the keys.
+	tokenContext = "webauthn session v1"
+)
+
+// Key is a signing key tagged with its ID.
+type Key struct {
+	ID     string // Sent with every token, must not contain '.'
+	Secret []byte // At least 32 bytes, keep it secret
+}
+
+// Claims are the contents of a session token.
+type Claims struct {
+	ID           string             `json:"jti"` // Random token ID, e.g., for revocation lists
+	UserID       webauthn.Base64URL `json:"sub"`
+	CredentialID string             `json:"cid"` // Base64url
+	UserVerified bool               `json:"uv"`
+	AMR          []string           `json:"amr"`       // See LoginResult.AMR
+	AuthTime     int64              `json:"auth_time"` // Unix time of the login
+	ExpiresAt    int64              `json:"exp"`       // Unix time
+}
+
+// Config holds the configuration of a Manager.
+type Config struct {
+	// Keys are the signing keys. The first one signs new tokens, all of them verify.
+	Keys []Key
+	// TTL is the lifetime of the tokens, defaults to 12 hours.
+	TTL time.Duration
+	// IsRevoked reports whether a validly signed token was revoked, e.g., on logout. Optional.
+	IsRevoked func(ctx context.Context, claims *Claims) (bool, error)
+	// CookieName is the name of the session cookie, defaults to "webauthn_session".
+	CookieName string
+	// CookiePath is the path of the session cookie, defaults to "/".
+	CookiePath string
+	// InsecureCookie drops the Secure attribute of the cookie, for local development over plain HTTP only.
+	InsecureCookie bool
+}
+
+// Manager issues and verifies session tokens.
+type Manager struct {
+	config     Config
+	keys       map[string][]byte
+	signingKID string
+}
+
+// New validates the configuration and creates a Manager.
+func New(config Config) (*Manager, error) {
+	if len(config.Keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	keys := make(map[string][]byte, len(config.Keys))
+	for _, key := range config.Keys {
+		if key.ID == "" || strings.Contains(key.ID, ".") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidKeyID, key.ID)
+		}
+		if len(key.Secret) < minKeyLength {
+			return nil, fmt.Errorf("%w: key %s", ErrKeyTooShort, key.ID)
+		}
+		if _, ok := keys[key.ID]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateKeyID, key.ID)
+		}
+		keys[key.ID] = key.Secret
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultTTL
+	}
+	if config.CookieName == "" {
+		config.CookieName = defaultCookieName
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	return &Manager{config: config, keys: keys, signingKID: config.Keys[0].ID}, nil
+}
+
+// Issue mints a token for a successful login. result.UserID must be set (FinishConditionalLogin and
+// the httpapi handler set it, after FinishLogin the caller does), result.CredentialID should be.
+func (m *Manager) Issue(result *webauthn.LoginResult) (string, *Claims, error) {
+	if result == nil {
+		return "", nil, ErrNilLoginResult
+	}
+	if len(result.UserID) == 0 {
+		return "", nil, ErrMissingUserID
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	claims := &Claims{
+		ID:           base64.RawURLEncoding.EncodeToString(id),
+		UserID:       result.UserID,
+		CredentialID: result.CredentialID,
+		UserVerified: result.UserVerified,
+		AMR:          result.AMR(),
+		AuthTime:     now.Unix(),
+		ExpiresAt:    now.Add(m.config.TTL).Unix(),
+	}
+	token, err := m.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// Verify checks the token's signature, expiry and revocation, and returns its claims.
+func (m *Manager) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	kid, payload := parts[0], parts[1]
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+	if !hmac.Equal(mac, tokenMAC(key, kid, payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(decoded, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpired
+	}
+	if m.config.IsRevoked != nil {
+		revoked, err := m.config.IsRevoked(ctx, &claims)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFailedRevokeCheck, err)
+		}
+		if revoked {
+			return nil, ErrRevoked
+		}
+	}
+	return &claims, nil
+}
+
+// sign serializes the claims and signs them with the first key.
+func (m *Manager) sign(claims *Claims) (string, error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	kid := m.signingKID
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	mac := tokenMAC(m.keys[kid], kid, payload)
+	return kid + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac), nil
+}
+
+// tokenMAC computes the MAC over the key ID and the encoded claims.
+func tokenMAC(key []byte, kid, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(tokenContext))
+	mac.Write([]byte{0})
+	mac.Write([]byte(kid + "." + payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,261 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	webauthn "github.com/MrBoombastic/WebAuthn2Go"
+)
+
+func testKey(id string, seed byte) Key {
+	secret := make([]byte, minKeyLength)
+	for i := range secret {
+		secret[i] = seed + byte(i)
+	}
+	return Key{ID: id, Secret: secret}
+}
+
+func newTestManager(t *testing.T, config Config) *Manager {
+	t.Helper()
+	if config.Keys == nil {
+		config.Keys = []Key{testKey("2026-10", 0)}
+	}
+	m, err := New(config)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m
+}
+
+func testResult() *webauthn.LoginResult {
+	return &webauthn.LoginResult{
+		UserID:       []byte{1, 2, 3},
+		CredentialID: "BAUG",
+		UserVerified: true,
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []Key
+		err  error
+	}{
+		{"valid", []Key{testKey("a", 0), testKey("b", 1)}, nil},
+		{"no keys", nil, ErrNoKeys},
+		{"short key", []Key{{ID: "a", Secret: make([]byte, minKeyLength-1)}}, ErrKeyTooShort},
+		{"empty key ID", []Key{testKey("", 0)}, ErrInvalidKeyID},
+		{"dot in key ID", []Key{testKey("a.b", 0)}, ErrInvalidKeyID},
+		{"duplicate key ID", []Key{testKey("a", 0), testKey("a", 1)}, ErrDuplicateKeyID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{Keys: tt.keys})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("New() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// The expected token was computed independently as
+// kid "." base64url(claims) "." base64url(HMAC-SHA256(key, "webauthn session v1" || 0x00 || kid "." payload)).
+func TestSignKnownAnswer(t *testing.T) {
+	m := newTestManager(t, Config{Keys: []Key{testKey("2026-10", 0)}})
+	claims := &Claims{
+		ID:           "AAAAAAAAAAAAAAAAAAAAAA",
+		UserID:       []byte{1, 2, 3},
+		CredentialID: "BAUG",
+		UserVerified: true,
+		AMR:          []string{"hwk", "user"},
+		AuthTime:     1700000000,
+		ExpiresAt:    1700043200,
+	}
+	const want = "2026-10.eyJqdGkiOiJBQUFBQUFBQUFBQUFBQUFBQUFBQUFBIiwic3ViIjoiQVFJRCIsImNpZCI6IkJBVUciLCJ1diI6dHJ1ZSwiYW1yIjpbImh3ayIsInVzZXIiXSwiYXV0aF90aW1lIjoxNzAwMDAwMDAwLCJleHAiOjE3MDAwNDMyMDB9.9l7aVtCSKY4C9kMhgZGuHAQebETjRbnjIeTevT-GPAU"
+
+	token, err := m.sign(claims)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if token != want {
+		t.Fatalf("sign() = %s, want %s", token, want)
+	}
+	// The signature is valid, but the token expired in 2023
+	if _, err := m.Verify(context.Background(), want); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrExpired)
+	}
+}
+
+func TestIssueVerify(t *testing.T) {
+	m := newTestManager(t, Config{TTL: time.Hour})
+	token, issued, err := m.Issue(testResult())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	claims, err := m.Verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.ID != issued.ID || string(claims.UserID) != "\x01\x02\x03" || claims.CredentialID != "BAUG" || !claims.UserVerified {
+		t.Errorf("Verify() claims = %+v, want %+v", claims, issued)
+	}
+	if want := []string{webauthn.AMRHardwareKey, webauthn.AMRUser}; !slices.Equal(claims.AMR, want) {
+		t.Errorf("AMR = %v, want %v", claims.AMR, want)
+	}
+	if ttl := claims.ExpiresAt - claims.AuthTime; ttl != int64(time.Hour/time.Second) {
+		t.Errorf("ExpiresAt - AuthTime = %d, want 3600", ttl)
+	}
+}
+
+func TestIssueErrors(t *testing.T) {
+	m := newTestManager(t, Config{})
+	if _, _, err := m.Issue(nil); !errors.Is(err, ErrNilLoginResult) {
+		t.Errorf("Issue(nil) error = %v, want %v", err, ErrNilLoginResult)
+	}
+	if _, _, err := m.Issue(&webauthn.LoginResult{}); !errors.Is(err, ErrMissingUserID) {
+		t.Errorf("Issue() without user ID error = %v, want %v", err, ErrMissingUserID)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	m := newTestManager(t, Config{})
+	token, _, err := m.Issue(testResult())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	parts := strings.Split(token, ".")
+	other := newTestManager(t, Config{Keys: []Key{testKey("2026-10", 100)}})
+	forged, _, err := other.Issue(testResult())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	expired, err := m.sign(&Claims{UserID: []byte{1}, ExpiresAt: time.Now().Add(-time.Second).Unix()})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	tampered, err := m.sign(&Claims{UserID: []byte{9}, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	tamperedParts := strings.Split(tampered, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"empty", "", ErrMalformedToken},
+		{"two parts", parts[0] + "." + parts[1], ErrMalformedToken},
+		{"four parts", token + ".x", ErrMalformedToken},
+		{"unknown key ID", "other." + parts[1] + "." + parts[2], ErrUnknownKeyID},
+		{"MAC not base64url", parts[0] + "." + parts[1] + ".!!", ErrMalformedToken},
+		{"MAC of another key", forged, ErrInvalidSignature},
+		{"payload swapped", parts[0] + "." + tamperedParts[1] + "." + parts[2], ErrInvalidSignature},
+		{"truncated MAC", token[:len(token)-3], ErrInvalidSignature},
+		{"expired", expired, ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Verify(context.Background(), tt.token); !errors.Is(err, tt.err) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := testKey("2026-04", 0), testKey("2026-10", 50)
+	before := newTestManager(t, Config{Keys: []Key{oldKey}})
+	token, _, err := before.Issue(testResult())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	rotated := newTestManager(t, Config{Keys: []Key{newKey, oldKey}})
+	if _, err := rotated.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify() with the old key still configured: %v", err)
+	}
+	newToken, _, err := rotated.Issue(testResult())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if !strings.HasPrefix(newToken, newKey.ID+".") {
+		t.Errorf("token %s isn't signed with the first key", newToken)
+	}
+
+	dropped := newTestManager(t, Config{Keys: []Key{newKey}})
+	if _, err := dropped.Verify(context.Background(), token); !errors.Is(err, ErrUnknownKeyID) {
+		t.Fatalf("Verify() after dropping the old key error = %v, want %v", err, ErrUnknownKeyID)
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	errStore := errors.New("store down")
+	tests := []struct {
+		name      string
+		isRevoked func(ctx context.Context, claims *Claims) (bool, error)
+		err       error
+	}{
+		{"not revoked", func(context.Context, *Claims) (bool, error) { return false, nil }, nil},
+		{"revoked", func(context.Context, *Claims) (bool, error) { return true, nil }, ErrRevoked},
+		{"check fails", func(context.Context, *Claims) (bool, error) { return false, errStore }, ErrFailedRevokeCheck},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, Config{IsRevoked: tt.isRevoked})
+			token, _, err := m.Issue(testResult())
+			if err != nil {
+				t.Fatalf("Issue: %v", err)
+			}
+			if _, err := m.Verify(context.Background(), token); !errors.Is(err, tt.err) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCookie(t *testing.T) {
+	m := newTestManager(t, Config{CookiePath: "/app"})
+	token, claims, err := m.Issue(testResult())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	m.SetCookie(rec, token, claims)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	cookie := cookies[0]
+	if cookie.Name != defaultCookieName || cookie.Path != "/app" || !cookie.Secure || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie attributes = %+v", cookie)
+	}
+	if cookie.Expires.Unix() != claims.ExpiresAt {
+		t.Errorf("cookie expires at %d, want %d", cookie.Expires.Unix(), claims.ExpiresAt)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/app", nil)
+	if _, err := m.FromRequest(r); !errors.Is(err, ErrMissingCookie) {
+		t.Fatalf("FromRequest() without cookie error = %v, want %v", err, ErrMissingCookie)
+	}
+	r.AddCookie(cookie)
+	got, err := m.FromRequest(r)
+	if err != nil {
+		t.Fatalf("FromRequest: %v", err)
+	}
+	if got.ID != claims.ID {
+		t.Errorf("FromRequest() ID = %s, want %s", got.ID, claims.ID)
+	}
+
+	rec = httptest.NewRecorder()
+	m.ClearCookie(rec)
+	if cleared := rec.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 || cleared[0].Value != "" {
+		t.Errorf("ClearCookie() cookies = %+v", cleared)
+	}
+}
@@ -0,0 +1,179 @@
+package webauthn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	// minTransactionProofKeyLength is the minimum length of Config.TransactionProofKey (bytes).
+	minTransactionProofKeyLength = 32
+	// transactionNonceLength is the length of the random nonce mixed into transaction challenges (bytes).
+	transactionNonceLength = 32
+	// transactionProofContext separates transaction proof MACs from other uses of the key.
+	transactionProofContext = "webauthn transaction proof v1"
+)
+
+// TransactionChallenge is a step-up challenge bound to one transaction, see BeginTransaction.
+// Store it server-side until FinishTransaction, and send only Options to the client.
+type TransactionChallenge struct {
+	Options *PublicKeyCredentialRequestOptions `json:"publicKey"`
+	Digest  Base64URL                          `json:"digest"` // Digest of the transaction, computed by the caller
+	Nonce   Base64URL                          `json:"nonce"`
+}
+
+// TransactionProof proves that a credential approved a transaction. It's signed with Config.TransactionProofKey,
+// so it can be handed to other services and checked with VerifyTransactionProof.
+type TransactionProof struct {
+	CredentialID string    `json:"credentialID"` // Base64url
+	UserVerified bool      `json:"userVerified"`
+	RPID         string    `json:"rpId"`
+	Digest       Base64URL `json:"digest"`
+	Nonce        Base64URL `json:"nonce"`
+	IssuedAt     int64     `json:"iat"` // Unix time
+	MAC          Base64URL `json:"mac"` // HMAC-SHA256 over the other fields
+}
+
+// TransactionResult holds the successful result of a step-up ceremony.
+type TransactionResult struct {
+	NewSignCount uint32           `json:"newSignCount"`
+	Proof        TransactionProof `json:"proof"`
+	Extensions   Extensions       `json:"extensions"`
+	Compliance   string           `json:"compliance,omitempty"` // Name of the compliance profile, empty without one
+}
+
+// BeginTransaction generates options for approving a specific action (e.g., a wire transfer) with an existing credential.
+// digest is the caller's digest of the transaction, e.g., SHA-256 of its canonical form. The challenge is
+// SHA-256(digest || nonce) with a random nonce, so every approval is unique even for identical transactions.
+func (w *WebAuthn) BeginTransaction(digest []byte, allowedCredentialIDs []string, opts ...Option) (*TransactionChallenge, error) {
+	if w == nil {
+		return nil, ErrNilInstance
+	}
+	if len(w.Config.TransactionProofKey) == 0 {
+		return nil, ErrMissingTransactionProofKey
+	}
+	if len(digest) == 0 {
+		return nil, ErrEmptyTransactionDigest
+	}
+	nonce := make([]byte, transactionNonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGeneratingChallenge, err)
+	}
+
+	options, err := w.BeginLogin(allowedCredentialIDs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	options.Challenge = transactionChallenge(digest, nonce)
+
+	return &TransactionChallenge{
+		Options: options,
+		Digest:  digest,
+		Nonce:   nonce,
+	}, nil
+}
+
+// FinishTransaction verifies the assertion for the stored transaction challenge and returns a signed proof
+// tying the credential, the UV flag and the transaction together. data.CredentialID is required,
+// and data.PublicKey must be the key stored for it.
+func (w *WebAuthn) FinishTransaction(data *LoginData, tx *TransactionChallenge) (*TransactionResult, error) {
+	if w == nil {
+		return nil, ErrNilInstance
+	}
+	if data == nil {
+		return nil, ErrNilLoginData
+	}
+	if len(w.Config.TransactionProofKey) == 0 {
+		return nil, ErrMissingTransactionProofKey
+	}
+	if tx == nil || tx.Options == nil {
+		return nil, ErrMissingTransactionChallenge
+	}
+	if data.CredentialID == "" {
+		return nil, ErrMissingCredentialID
+	}
+	if len(tx.Options.AllowCredentials) > 0 && !hasAllowedCredential(tx.Options.AllowCredentials, data.CredentialID) {
+		return nil, ErrCredentialNotFound
+	}
+	// The stored challenge must still be the one derived from the transaction
+	if subtle.ConstantTimeCompare([]byte(tx.Options.Challenge), []byte(transactionChallenge(tx.Digest, tx.Nonce))) == 0 {
+		return nil, ErrChallengeMismatch
+	}
+
+	c := *data
+	c.Options = tx.Options
+	res, err := w.validateAssertion(&c, loginAssertionCheck)
+	if err != nil {
+		return nil, fmt.Errorf("assertion validation failed: %w", err)
+	}
+
+	proof := TransactionProof{
+		CredentialID: data.CredentialID,
+		UserVerified: res.UserVerified,
+		RPID:         res.RPID,
+		Digest:       tx.Digest,
+		Nonce:        tx.Nonce,
+		IssuedAt:     time.Now().Unix(),
+	}
+	proof.MAC = w.transactionProofMAC(&proof)
+
+	return &TransactionResult{
+		NewSignCount: res.NewSignCount,
+		Proof:        proof,
+		Extensions:   res.Extensions,
+		Compliance:   res.Compliance,
+	}, nil
+}
+
+// VerifyTransactionProof checks that the proof was issued by FinishTransaction for the transaction digest.
+// Checking UserVerified and the age of the proof is up to the caller.
+func (w *WebAuthn) VerifyTransactionProof(proof *TransactionProof, digest []byte) error {
+	if w == nil {
+		return ErrNilInstance
+	}
+	if len(w.Config.TransactionProofKey) == 0 {
+		return ErrMissingTransactionProofKey
+	}
+	if proof == nil {
+		return ErrInvalidTransactionProof
+	}
+	if !hmac.Equal(proof.MAC, w.transactionProofMAC(proof)) {
+		return fmt.Errorf("%w: bad MAC", ErrInvalidTransactionProof)
+	}
+	if !hmac.Equal(proof.Digest, digest) {
+		return fmt.Errorf("%w: digest mismatch", ErrInvalidTransactionProof)
+	}
+	return nil
+}
+
+// transactionChallenge derives the base64url encoded challenge SHA-256(digest || nonce).
+func transactionChallenge(digest, nonce []byte) string {
+	h := sha256.New()
+	h.Write(digest)
+	h.Write(nonce)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// transactionProofMAC computes the proof MAC over length-prefixed fields, so they can't be shifted into each other.
+func (w *WebAuthn) transactionProofMAC(p *TransactionProof) []byte {
+	mac := hmac.New(sha256.New, w.Config.TransactionProofKey)
+	mac.Write([]byte(transactionProofContext))
+	mac.Write([]byte{0})
+	for _, field := range [][]byte{[]byte(p.CredentialID), []byte(p.RPID), p.Digest, p.Nonce} {
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		mac.Write(field)
+	}
+	uv := byte(0)
+	if p.UserVerified {
+		uv = 1
+	}
+	mac.Write([]byte{uv})
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(p.IssuedAt)))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,142 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func newTransactionWebAuthn(t *testing.T, proofKey []byte) *WebAuthn {
+	t.Helper()
+	w, err := New(&Config{
+		RPID:                "example.com",
+		RPDisplayName:       "Example",
+		RPOrigins:           []string{"https://example.com"},
+		Timeout:             60000,
+		UserVerification:    UVPreferred,
+		Attestation:         AttestationNone,
+		TransactionProofKey: proofKey,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return w
+}
+
+// approveTransaction runs a step-up ceremony for the digest and returns its proof.
+func approveTransaction(t *testing.T, w *WebAuthn, key *assertionKey, digest []byte) *TransactionProof {
+	t.Helper()
+	tx, err := w.BeginTransaction(digest, []string{"BAUG"})
+	if err != nil {
+		t.Fatalf("BeginTransaction: %v", err)
+	}
+	res, err := w.FinishTransaction(key.loginData(t, tx.Options.Challenge), tx)
+	if err != nil {
+		t.Fatalf("FinishTransaction: %v", err)
+	}
+	return &res.Proof
+}
+
+func TestTransactionProof(t *testing.T) {
+	w := newTransactionWebAuthn(t, bytes.Repeat([]byte{1}, 32))
+	key := newAssertionKey(t)
+	digest := sha256.Sum256([]byte(`{"to":"DE89370400440532013000","amount":"100.00"}`))
+	proof := approveTransaction(t, w, key, digest[:])
+	if proof.CredentialID != "BAUG" || !proof.UserVerified || proof.RPID != "example.com" || !bytes.Equal(proof.Digest, digest[:]) {
+		t.Errorf("FinishTransaction() proof = %+v", proof)
+	}
+	if err := w.VerifyTransactionProof(proof, digest[:]); err != nil {
+		t.Fatalf("VerifyTransactionProof() error = %v", err)
+	}
+
+	otherDigest := sha256.Sum256([]byte(`{"to":"DE89370400440532013000","amount":"9999.00"}`))
+	tests := []struct {
+		name   string
+		modify func(p *TransactionProof)
+		digest []byte
+	}{
+		{"other transaction", func(p *TransactionProof) {}, otherDigest[:]},
+		{"tampered digest", func(p *TransactionProof) { p.Digest = otherDigest[:] }, otherDigest[:]},
+		{"tampered nonce", func(p *TransactionProof) { p.Nonce = append(Base64URL{}, p.Nonce...); p.Nonce[0] ^= 1 }, digest[:]},
+		{"tampered MAC", func(p *TransactionProof) { p.MAC = append(Base64URL{}, p.MAC...); p.MAC[0] ^= 1 }, digest[:]},
+		{"tampered user verification", func(p *TransactionProof) { p.UserVerified = false }, digest[:]},
+		{"tampered credential", func(p *TransactionProof) { p.CredentialID = "BwgJ" }, digest[:]},
+		{"tampered issue time", func(p *TransactionProof) { p.IssuedAt++ }, digest[:]},
+		{"no MAC", func(p *TransactionProof) { p.MAC = nil }, digest[:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := *proof
+			tt.modify(&tampered)
+			if err := w.VerifyTransactionProof(&tampered, tt.digest); !errors.Is(err, ErrInvalidTransactionProof) {
+				t.Errorf("VerifyTransactionProof() error = %v, want %v", err, ErrInvalidTransactionProof)
+			}
+		})
+	}
+
+	// Another key doesn't accept the proof
+	other := newTransactionWebAuthn(t, bytes.Repeat([]byte{2}, 32))
+	if err := other.VerifyTransactionProof(proof, digest[:]); !errors.Is(err, ErrInvalidTransactionProof) {
+		t.Errorf("VerifyTransactionProof() with another key error = %v, want %v", err, ErrInvalidTransactionProof)
+	}
+}
+
+func TestFinishTransactionChallenge(t *testing.T) {
+	w := newTransactionWebAuthn(t, bytes.Repeat([]byte{1}, 32))
+	key := newAssertionKey(t)
+	digest := sha256.Sum256([]byte("transfer 100.00"))
+	otherDigest := sha256.Sum256([]byte("transfer 9999.00"))
+
+	// The stored challenge was derived from another digest than the one stored with it
+	tx, err := w.BeginTransaction(digest[:], nil)
+	if err != nil {
+		t.Fatalf("BeginTransaction: %v", err)
+	}
+	swapped := *tx
+	swapped.Digest = otherDigest[:]
+	if _, err := w.FinishTransaction(key.loginData(t, tx.Options.Challenge), &swapped); !errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("FinishTransaction() with a swapped digest error = %v, want %v", err, ErrChallengeMismatch)
+	}
+
+	// An approval of one transaction doesn't finish another
+	otherTx, err := w.BeginTransaction(otherDigest[:], nil)
+	if err != nil {
+		t.Fatalf("BeginTransaction: %v", err)
+	}
+	if _, err := w.FinishTransaction(key.loginData(t, tx.Options.Challenge), otherTx); !errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("FinishTransaction() of another transaction error = %v, want %v", err, ErrChallengeMismatch)
+	}
+
+	// Identical transactions get unique challenges
+	again, err := w.BeginTransaction(digest[:], nil)
+	if err != nil {
+		t.Fatalf("BeginTransaction: %v", err)
+	}
+	if again.Options.Challenge == tx.Options.Challenge {
+		t.Errorf("BeginTransaction() reused challenge %s", tx.Options.Challenge)
+	}
+	if want := transactionChallenge(digest[:], again.Nonce); again.Options.Challenge != want {
+		t.Errorf("BeginTransaction() challenge = %s, want %s", again.Options.Challenge, want)
+	}
+}
+
+// Length prefixes keep adjacent fields from being shifted into each other.
+func TestTransactionProofMACBoundaries(t *testing.T) {
+	w := newTransactionWebAuthn(t, bytes.Repeat([]byte{1}, 32))
+	tests := []struct {
+		name string
+		a, b TransactionProof
+	}{
+		{"credential and RP ID", TransactionProof{CredentialID: "ab", RPID: "c"}, TransactionProof{CredentialID: "a", RPID: "bc"}},
+		{"RP ID and digest", TransactionProof{RPID: "ab", Digest: []byte("c")}, TransactionProof{RPID: "a", Digest: []byte("bc")}},
+		{"digest and nonce", TransactionProof{Digest: []byte("ab"), Nonce: []byte("c")}, TransactionProof{Digest: []byte("a"), Nonce: []byte("bc")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if bytes.Equal(w.transactionProofMAC(&tt.a), w.transactionProofMAC(&tt.b)) {
+				t.Errorf("transactionProofMAC(%+v) = transactionProofMAC(%+v)", tt.a, tt.b)
+			}
+		})
+	}
+}
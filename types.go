@@ -1,11 +1,23 @@
 package webauthn
 
-// Constants for COSE Algorithms
-const (
-	algES256 int64 = -7   // ECDSA w/ SHA-256
-	algRS256 int64 = -257 // RSASSA-PKCS1-v1_5 w/ SHA-256
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/MrBoombastic/WebAuthn2Go/cose"
+	"strings"
 )
 
+// DefaultAlgorithms are offered when Config.Algorithms is empty, in order of preference.
+// ES256 comes first as every authenticator supports it, RS256 last for Windows Hello.
+var DefaultAlgorithms = []cose.Algorithm{
+	cose.AlgES256,
+	cose.AlgEdDSA,
+	cose.AlgES384,
+	cose.AlgES512,
+	cose.AlgPS256,
+	cose.AlgRS256,
+}
+
 // AttestationPreference defines the level of attestation requested.
 type AttestationPreference string
 
@@ -44,6 +56,109 @@ func (uv UserVerificationRequirement) IsValid() bool {
 	}
 }
 
+// ResidentKeyRequirement defines whether a discoverable credential (passkey) should be created.
+type ResidentKeyRequirement string
+
+const (
+	ResidentKeyRequired    ResidentKeyRequirement = "required"
+	ResidentKeyPreferred   ResidentKeyRequirement = "preferred"
+	ResidentKeyDiscouraged ResidentKeyRequirement = "discouraged"
+)
+
+// IsValid checks if the ResidentKeyRequirement is one of the defined constants.
+func (rk ResidentKeyRequirement) IsValid() bool {
+	switch rk {
+	case ResidentKeyRequired, ResidentKeyPreferred, ResidentKeyDiscouraged:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuthenticatorAttachment restricts registration to platform or roaming (cross-platform) authenticators.
+type AuthenticatorAttachment string
+
+const (
+	AttachmentPlatform      AuthenticatorAttachment = "platform"
+	AttachmentCrossPlatform AuthenticatorAttachment = "cross-platform"
+)
+
+// IsValid checks if the AuthenticatorAttachment is one of the defined constants.
+func (a AuthenticatorAttachment) IsValid() bool {
+	switch a {
+	case AttachmentPlatform, AttachmentCrossPlatform:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuthenticatorTransport is a transport the client can use to reach an authenticator, reported at registration.
+type AuthenticatorTransport string
+
+const (
+	TransportUSB       AuthenticatorTransport = "usb"
+	TransportNFC       AuthenticatorTransport = "nfc"
+	TransportBLE       AuthenticatorTransport = "ble"
+	TransportSmartCard AuthenticatorTransport = "smart-card"
+	TransportHybrid    AuthenticatorTransport = "hybrid"
+	TransportInternal  AuthenticatorTransport = "internal"
+)
+
+// IsValid checks if the AuthenticatorTransport is one of the defined constants.
+func (t AuthenticatorTransport) IsValid() bool {
+	switch t {
+	case TransportUSB, TransportNFC, TransportBLE, TransportSmartCard, TransportHybrid, TransportInternal:
+		return true
+	default:
+		return false
+	}
+}
+
+// knownTransports returns the transports that are one of the defined constants. Clients may report transports
+// added to the spec later, they are dropped rather than failing the ceremony.
+func knownTransports(transports []AuthenticatorTransport) []AuthenticatorTransport {
+	var known []AuthenticatorTransport
+	for _, t := range transports {
+		if t.IsValid() {
+			known = append(known, t)
+		}
+	}
+	return known
+}
+
+// AuthenticatorSelection holds the authenticator requirements of a registration.
+type AuthenticatorSelection struct {
+	AuthenticatorAttachment AuthenticatorAttachment     `json:"authenticatorAttachment,omitempty"`
+	ResidentKey             ResidentKeyRequirement      `json:"residentKey,omitempty"`
+	RequireResidentKey      bool                        `json:"requireResidentKey,omitempty"` // WebAuthn Level 1 form of ResidentKeyRequired
+	UserVerification        UserVerificationRequirement `json:"userVerification,omitempty"`
+}
+
+// Base64URL is a byte slice encoded as an unpadded base64url string in JSON.
+type Base64URL []byte
+
+// MarshalJSON encodes the bytes as an unpadded base64url string.
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes a base64url string, with or without padding. Standard base64 is accepted as well,
+// as encoding/json wrote byte slices (e.g., UserEntity.ID before it became a Base64URL) in that form.
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(strings.TrimRight(s, "="))
+	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
 // Config holds the configuration for the WebAuthn library.
 // Ensure RPOrigin(s) are set correctly for security checks.
 type Config struct {
@@ -54,12 +169,41 @@ type Config struct {
 	UserVerification UserVerificationRequirement // Default User Verification Requirement
 	Attestation      AttestationPreference       // Default Attestation Preference
 	Debug            bool                        // Enable debug logging
+	// SkipOriginRPIDCheck disables checking that every RPOrigin is the RPID or its subdomain.
+	// Prefer RelatedOrigins for origins on other domains, which are exempt from the check anyway.
+	SkipOriginRPIDCheck bool
+	// RelatedOrigins are origins on other domains allowed to use RPID via Related Origin Requests
+	// (e.g., ["https://example.co.uk", "https://example.de"]). They must be listed in the document
+	// served at https://<RPID>/.well-known/webauthn, see WebAuthn.RelatedOriginsJSON.
+	RelatedOrigins []string
+	// ConditionalTimeout is the lifetime of conditional mediation challenges (milliseconds, defaults to 30 minutes).
+	ConditionalTimeout uint32
+	// ConditionalChallenges stores conditional mediation challenges, defaults to an in-memory store.
+	ConditionalChallenges ChallengeStore
+	// FakeCredentialSecret is the HMAC key for fake credentials of unknown users, see BeginLoginForUser.
+	// Keep it secret and stable across restarts, at least 32 bytes.
+	FakeCredentialSecret []byte
+	// TransactionProofKey is the HMAC key for transaction proofs, see BeginTransaction.
+	// Keep it secret and stable across restarts, at least 32 bytes.
+	TransactionProofKey []byte
+	// Extensions holds decoders for custom extensions, see Extension. Built-in extensions are always supported.
+	Extensions []ExtensionDecoder
+	// Limiter counts failed assertions per user, credential and client IP and locks them out, see NewMemoryLimiter.
+	// Optional, attempts are not limited without it.
+	Limiter Limiter
+	// Algorithms are the COSE algorithms offered in pubKeyCredParams, in order of preference.
+	// Defaults to DefaultAlgorithms.
+	Algorithms []cose.Algorithm
+	// Compliance restricts the accepted cryptography, e.g., ProfileFIPS1403(). Optional.
+	Compliance *ComplianceProfile
 }
 
 // WebAuthn struct holds the configuration and manages WebAuthn operations.
 type WebAuthn struct {
 	Config          *Config
 	parsedRPOrigins []parsedOriginData // Pre-parsed origins for efficient checking
+	// Pre-parsed related origins, accepted although their hosts are outside RPID
+	parsedRelatedOrigins []parsedOriginData
 }
 
 // parsedOriginData holds pre-parsed and normalized components of an allowed origin.
@@ -71,8 +215,18 @@ type parsedOriginData struct {
 
 // RegistrationData holds the inputs for completing a registration ceremony.
 type RegistrationData struct {
-	ClientDataJSON    string `json:"clientDataJSON"`
-	AttestationObject string
+	ClientDataJSON         string `json:"clientDataJSON"`
+	AttestationObject      string
+	ClientExtensionResults ClientExtensionResults `json:"clientExtensionResults"`
+	// Options returned by BeginRegistration for this ceremony. Optional, but required for checks
+	// depending on what was requested, like the challenge or credProtect enforcement.
+	// Never decoded from JSON, it must come from the server side, or the client could pick the challenge.
+	Options *BeginRegistrationOptions `json:"-"`
+	// Optional members of the response reported by the client, see PublicKeyCredential
+	Transports              []AuthenticatorTransport `json:"transports,omitempty"`
+	AuthenticatorAttachment AuthenticatorAttachment  `json:"authenticatorAttachment,omitempty"`
+	PublicKeyAlgorithm      *int64                   `json:"publicKeyAlgorithm,omitempty"` // Checked against the COSE key
+	PublicKeySPKI           string                   `json:"publicKey,omitempty"`          // Base64url DER SubjectPublicKeyInfo, checked against the COSE key
 }
 
 // RegistrationResult holds the successful result of a registration ceremony.
@@ -82,25 +236,57 @@ type RegistrationResult struct {
 	AAGUID            string
 	AuthenticatorName string
 	SignCount         uint32
+	Discoverable      *bool      // Reported by the credProps extension, nil if the client didn't report it
+	Extensions        Extensions // Validated outputs of the requested extensions
+	// Transports reported by the client without unknown ones, store them for WithCredentialTransports
+	Transports              []AuthenticatorTransport
+	AuthenticatorAttachment AuthenticatorAttachment // Reported by the client, empty if unknown
+	PublicKeyAlgorithm      int64                   // Negotiated COSE algorithm of PublicKey, one of the offered pubKeyCredParams
+	PublicKeySPKI           []byte                  // PublicKey as DER SubjectPublicKeyInfo
+	Compliance              string                  // Name of the compliance profile the registration was checked against
+	// Deprecated: use Extensions.PRF.
+	PRF *PRFOutputs
+	// Deprecated: use Extensions.LargeBlob.
+	LargeBlob *LargeBlobOutputs
+	// Deprecated: use Extensions.CredProtect.
+	CredProtect CredProtectLevel
 }
 
 // LoginResult holds the successful result of an authentication (login) ceremony.
 type LoginResult struct {
-	NewSignCount uint32 `json:"newSignCount"`
-	UserVerified bool   `json:"userVerified"`
+	NewSignCount uint32     `json:"newSignCount"`
+	UserVerified bool       `json:"userVerified"`
+	CredentialID string     `json:"credentialID,omitempty"` // Set by FinishConditionalLogin
+	UserID       []byte     `json:"userID,omitempty"`       // Set by FinishConditionalLogin
+	RPID         string     `json:"rpId"`                   // RP ID the assertion matched, the AppID for legacy U2F credentials
+	Extensions   Extensions `json:"extensions"`             // Validated outputs of the requested extensions
+	// BackupEligible is set for credentials that can be synced between devices (e.g., passkeys in a password manager)
+	BackupEligible bool `json:"backupEligible"`
+	BackedUp       bool `json:"backedUp"` // Credential is currently backed up
+	// Compliance is the name of the compliance profile the assertion was checked against, empty without one
+	Compliance string `json:"compliance,omitempty"`
+	// Deprecated: use Extensions.PRF.
+	PRF *PRFOutputs `json:"prf,omitempty"`
+	// Deprecated: use Extensions.LargeBlob.
+	LargeBlob *LargeBlobOutputs `json:"largeBlob,omitempty"`
 }
 
 // ValidationOutput holds results from the internal validateAssertion method.
 type ValidationOutput struct {
-	NewSignCount uint32 `json:"newSignCount"`
-	UserVerified bool   `json:"userVerified"`
+	NewSignCount   uint32     `json:"newSignCount"`
+	UserVerified   bool       `json:"userVerified"`
+	RPID           string     `json:"rpId"`       // RP ID the assertion matched
+	Extensions     Extensions `json:"extensions"` // Validated outputs of the requested extensions
+	BackupEligible bool       `json:"backupEligible"`
+	BackedUp       bool       `json:"backedUp"`
+	Compliance     string     `json:"compliance,omitempty"`
 }
 
 // UserEntity represents the user entity
 type UserEntity struct {
-	ID          []byte `json:"id"`
-	Name        string `json:"name"`
-	DisplayName string `json:"displayName"`
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
 }
 
 // CredentialParameter defines a credential parameter
@@ -109,15 +295,18 @@ type CredentialParameter struct {
 	Alg  int64  `json:"alg"`
 }
 
-// BeginRegistrationOptions holds options for navigator.credentials.create()
+// BeginRegistrationOptions holds options for navigator.credentials.create(). Its JSON form is
+// PublicKeyCredentialCreationOptionsJSON, so it can be passed to PublicKeyCredential.parseCreationOptionsFromJSON().
 type BeginRegistrationOptions struct {
-	Challenge        string                      `json:"challenge"`
-	RP               RelyingPartyEntity          `json:"rp"`
-	User             UserEntity                  `json:"user"`
-	PubKeyCredParams []CredentialParameter       `json:"pubKeyCredParams"`
-	Timeout          uint32                      `json:"timeout"`
-	Attestation      AttestationPreference       `json:"attestation"`
-	UserVerification UserVerificationRequirement `json:"userVerification,omitempty"`
+	Challenge              string                          `json:"challenge"`
+	RP                     RelyingPartyEntity              `json:"rp"`
+	User                   UserEntity                      `json:"user"`
+	PubKeyCredParams       []CredentialParameter           `json:"pubKeyCredParams"`
+	Timeout                uint32                          `json:"timeout"`
+	Attestation            AttestationPreference           `json:"attestation"`
+	AuthenticatorSelection AuthenticatorSelection          `json:"authenticatorSelection"`
+	ExcludeCredentials     []PublicKeyCredentialDescriptor `json:"excludeCredentials,omitempty"`
+	Extensions             ExtensionInputs                 `json:"extensions,omitempty"`
 }
 
 type RelyingPartyEntity struct {
@@ -127,17 +316,20 @@ type RelyingPartyEntity struct {
 
 // PublicKeyCredentialDescriptor defines allowed credentials for login
 type PublicKeyCredentialDescriptor struct {
-	Type string `json:"type"`
-	ID   string `json:"id"`
+	Type       string                   `json:"type"`
+	ID         string                   `json:"id"`
+	Transports []AuthenticatorTransport `json:"transports,omitempty"` // Hints for the client, see WithCredentialTransports
 }
 
-// PublicKeyCredentialRequestOptions holds options for navigator.credentials.get()
+// PublicKeyCredentialRequestOptions holds options for navigator.credentials.get(). Its JSON form is
+// PublicKeyCredentialRequestOptionsJSON, so it can be passed to PublicKeyCredential.parseRequestOptionsFromJSON().
 type PublicKeyCredentialRequestOptions struct {
 	Challenge        string                          `json:"challenge"`
 	Timeout          uint32                          `json:"timeout"`
 	RPID             string                          `json:"rpId"`
 	AllowCredentials []PublicKeyCredentialDescriptor `json:"allowCredentials"`
 	UserVerification UserVerificationRequirement     `json:"userVerification"`
+	Extensions       ExtensionInputs                 `json:"extensions,omitempty"`
 }
 
 type attestationObject struct {
@@ -155,9 +347,20 @@ type ClientData struct {
 }
 
 type LoginData struct {
-	ClientDataJSON  string `json:"clientDataJSON"`
-	AuthData        string `json:"authData"`
-	Signature       string `json:"signature"`
-	StoredSignCount uint32 `json:"storedSignCount"`
-	PublicKey       []byte `json:"publicKey"`
+	ClientDataJSON         string                 `json:"clientDataJSON"`
+	AuthData               string                 `json:"authData"`
+	Signature              string                 `json:"signature"`
+	StoredSignCount        uint32                 `json:"storedSignCount"`
+	PublicKey              []byte                 `json:"publicKey"`
+	CredentialID           string                 `json:"credentialID,omitempty"` // Base64url, required by FinishConditionalLogin
+	UserHandle             string                 `json:"userHandle,omitempty"`   // Base64url, optional
+	ClientExtensionResults ClientExtensionResults `json:"clientExtensionResults"`
+	// Options returned by BeginLogin for this ceremony. Optional, but required for checks
+	// depending on what was requested, like the challenge or the appid extension.
+	// Never decoded from JSON, it must come from the server side, or the client could pick e.g. the AppID.
+	Options *PublicKeyCredentialRequestOptions `json:"-"`
+	// UserID is the owner of the credential as stored on the server (never the unverified UserHandle),
+	// and ClientIP the address of the client, both used as Config.Limiter keys.
+	UserID   []byte `json:"-"`
+	ClientIP string `json:"-"`
 }
@@ -3,43 +3,135 @@ package webauthn
 import (
 	"crypto/sha256"
 	"crypto/subtle"
+	"errors"
 	"fmt"
+	"github.com/MrBoombastic/WebAuthn2Go/cose"
 	"github.com/MrBoombastic/WebAuthn2Go/utils"
-	"github.com/go-webauthn/webauthn/protocol/webauthncose"
 )
 
+// assertionCheck holds the ceremony specific parts of assertion validation.
+type assertionCheck struct {
+	clientDataType  string                                        // Expected ClientData.Type
+	typeErr         error                                         // Returned if the type doesn't match
+	isAllowedOrigin func(origin string) (allowed bool, err error) // Defaults to WebAuthn.isAllowedOrigin
+	checkClientData func(clientDataJSON []byte) error             // Validates ceremony specific client data, optional
+}
+
+// loginAssertionCheck validates regular WebAuthn assertions.
+var loginAssertionCheck = assertionCheck{clientDataType: "webauthn.get", typeErr: ErrTypeNotWebauthnGet}
+
 // ValidateLoginData performs the core cryptographic verification of an assertion.
 func (w *WebAuthn) ValidateLoginData(c *LoginData) (out ValidationOutput, err error) {
-	// Parse and validate ClientData
-	var clientData ClientData
-	cdb64, err := clientData.ParseWithB64(c.ClientDataJSON)
+	return w.validateAssertion(c, loginAssertionCheck)
+}
+
+// validateAssertion verifies an assertion of any ceremony built on navigator.credentials.get(),
+// within the limits of Config.Limiter.
+func (w *WebAuthn) validateAssertion(c *LoginData, check assertionCheck) (ValidationOutput, error) {
+	keys := c.limitKeys()
+	if err := w.checkLimiter(keys); err != nil {
+		return ValidationOutput{}, err
+	}
+	parsed, err := w.parseAssertion(c)
 	if err != nil {
-		return out, fmt.Errorf("%w: %w", ErrFailedUnmarshalClientData, err)
+		// Malformed assertions say nothing about the credential, so they only count against the client
+		return ValidationOutput{}, w.joinLimiter(err, ipLimitKeys(keys))
+	}
+	// The credential is resolved by the caller, every failure from here on is a guess against it and its user
+	out, err := w.verifyAssertion(c, parsed, check)
+	return out, w.joinLimiter(err, keys)
+}
+
+// joinLimiter reports the outcome err of an attempt for keys and joins any limiter error to it.
+func (w *WebAuthn) joinLimiter(err error, keys []LimitKey) error {
+	if recordErr := w.recordLimiter(keys, err); recordErr != nil {
+		return errors.Join(err, recordErr)
+	}
+	return err
+}
+
+// parsedAssertion holds the decoded parts of an assertion.
+type parsedAssertion struct {
+	clientData     ClientData
+	clientDataJSON []byte
+	authData       []byte
+	authDataParsed *ParsedAuthData
+	signature      []byte
+	key            cose.PublicKey
+}
+
+// parseAssertion decodes the assertion and the stored credential public key, without checking them.
+func (w *WebAuthn) parseAssertion(c *LoginData) (*parsedAssertion, error) {
+	var p parsedAssertion
+	var err error
+	if p.clientDataJSON, err = p.clientData.ParseWithB64(c.ClientDataJSON); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailedUnmarshalClientData, err)
+	}
+	if p.authData, err = utils.DecodeBase64URL(c.AuthData); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailedDecodeAuthData, err)
+	}
+	if p.authDataParsed, err = w.ParseAuthenticatorData(p.authData); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailedParseClientData, err)
 	}
+	if p.signature, err = utils.DecodeBase64URL(c.Signature); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailedDecodeSignature, err)
+	}
+	if p.key, err = parseStoredPublicKey(c.PublicKey); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
 
-	if clientData.Type != "webauthn.get" {
-		return out, fmt.Errorf("%w, got %s", ErrTypeNotWebauthnGet, clientData.Type)
+// verifyAssertion does the actual verification of validateAssertion.
+func (w *WebAuthn) verifyAssertion(c *LoginData, p *parsedAssertion, check assertionCheck) (out ValidationOutput, err error) {
+	// Validate ClientData
+	clientData := p.clientData
+	if clientData.Type != check.clientDataType {
+		return out, fmt.Errorf("%w, got %s", check.typeErr, clientData.Type)
 	}
 
-	if allowed, err := w.isAllowedOrigin(clientData.RPOrigin); !allowed {
+	isAllowedOrigin := check.isAllowedOrigin
+	if isAllowedOrigin == nil {
+		isAllowedOrigin = w.isAllowedOrigin
+	}
+	if allowed, err := isAllowedOrigin(clientData.RPOrigin); !allowed {
 		return ValidationOutput{}, err
 	}
 
-	// Parse and validate AuthenticatorData
-	decodedAuthData, err := utils.DecodeBase64URL(c.AuthData)
-	if err != nil {
-		return out, fmt.Errorf("%w: %w", ErrFailedDecodeAuthData, err)
+	if check.checkClientData != nil {
+		if err := check.checkClientData(p.clientDataJSON); err != nil {
+			return out, err
+		}
+	}
+
+	if c.Options != nil && subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(c.Options.Challenge)) == 0 {
+		return out, ErrChallengeMismatch
+	}
+
+	authDataParsed := p.authDataParsed
+	// Validate extension outputs against the requested extensions, outputs are ignored without the options
+	var inputs ExtensionInputs
+	if c.Options != nil {
+		inputs = c.Options.Extensions
 	}
-	authDataParsed, err := w.ParseAuthenticatorData(decodedAuthData)
+	exts, err := w.decodeExtensions(CeremonyAuthentication, inputs)
 	if err != nil {
-		return out, fmt.Errorf("%w: %w", ErrFailedParseClientData, err)
+		return out, err
+	}
+	if out.Extensions, err = validateExtensions(CeremonyAuthentication, exts, c.ClientExtensionResults, authDataParsed.Extensions, c.Options != nil); err != nil {
+		return out, err
 	}
 
-	// Verify AuthenticatorData RP ID Hash
-	rpIDHashBytes := sha256.Sum256([]byte(w.Config.RPID))
+	// Verify AuthenticatorData RP ID Hash, legacy U2F credentials are scoped to the AppID instead
+	rpID, err := w.expectedLoginRPID(c, out.Extensions)
+	if err != nil {
+		return out, err
+	}
+	rpIDHashBytes := sha256.Sum256([]byte(rpID))
 	if subtle.ConstantTimeCompare(authDataParsed.RPIDHash, rpIDHashBytes[:]) == 0 {
 		return out, ErrRPIDHashMismatch
 	}
+	out.RPID = rpID
 
 	// Verify User Present flag (UP)
 	if authDataParsed.Flags&0x01 == 0 {
@@ -47,22 +139,18 @@ func (w *WebAuthn) ValidateLoginData(c *LoginData) (out ValidationOutput, err er
 	}
 	// Set User Verified flag based on UV flag (bit 2)
 	out.UserVerified = (authDataParsed.Flags & 0x04) != 0
-
-	decodedSignatureData, err := utils.DecodeBase64URL(c.Signature)
-	if err != nil {
-		return out, fmt.Errorf("%w: %w", ErrFailedDecodeSignature, err)
-	}
+	// Backup Eligibility (BE, bit 3) and Backup State (BS, bit 4)
+	out.BackupEligible = (authDataParsed.Flags & 0x08) != 0
+	out.BackedUp = (authDataParsed.Flags & 0x10) != 0
 
 	// Verify Signature
-	clientDataHashBytes := sha256.Sum256(cdb64) // Use crypto/sha256
-	verificationData := append(decodedAuthData, clientDataHashBytes[:]...)
-
-	key, _ := webauthncose.ParsePublicKey(c.PublicKey)
-	validSignature, err := webauthncose.VerifySignature(key, verificationData, decodedSignatureData)
-	if err != nil {
-		return out, fmt.Errorf("%w: %w", ErrSignatureVerification, err)
+	clientDataHashBytes := sha256.Sum256(p.clientDataJSON)
+	verificationData := append(p.authData, clientDataHashBytes[:]...)
+	if err := w.checkKeyCompliance(p.key); err != nil {
+		return out, err
 	}
-	if !validSignature {
+	out.Compliance = w.complianceName()
+	if p.key.Verify(verificationData, p.signature) != nil {
 		return out, ErrInvalidSignature
 	}
 
@@ -3,18 +3,25 @@ package webauthn
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/gofiber/fiber/v2/log"
+	"github.com/MrBoombastic/WebAuthn2Go/cose"
+	"github.com/MrBoombastic/WebAuthn2Go/psl"
+	"log"
 	"net/url"
 	"strings"
 )
 
 // New creates a new WebAuthn instance with the provided configuration.
-// It preparses and validates the RPOrigins.
+// It preparses and validates the RPOrigins, and checks them against the RPID.
+// The config is copied, so defaults are filled in without changing it, and later changes to it have no effect.
 func New(config *Config) (*WebAuthn, error) {
 	if config == nil {
 		return nil, ErrNilConfig
 	}
+	// Work on a copy, so the defaults filled in below don't leak into the caller's config
+	copied := *config
+	config = &copied
 
 	if !config.Attestation.IsValid() {
 		return nil, fmt.Errorf("%w: %v", ErrAttestationNotSupported, config.Attestation)
@@ -31,54 +38,143 @@ func New(config *Config) (*WebAuthn, error) {
 	if config.RPID == "" {
 		return nil, ErrEmptyRPID
 	}
+	if psl.IsPublicSuffix(config.RPID) {
+		return nil, fmt.Errorf("%w: %s", ErrRPIDIsPublicSuffix, config.RPID)
+	}
 	if config.RPDisplayName == "" {
 		return nil, ErrEmptyRPDisplayName
 	}
 	if config.Timeout <= 0 {
 		return nil, ErrInvalidTimeout
 	}
+	if len(config.FakeCredentialSecret) > 0 && len(config.FakeCredentialSecret) < minFakeCredentialSecretLength {
+		return nil, ErrFakeCredentialSecretTooShort
+	}
+	if len(config.TransactionProofKey) > 0 && len(config.TransactionProofKey) < minTransactionProofKeyLength {
+		return nil, ErrTransactionProofKeyTooShort
+	}
 
-	parsedOrigins := make([]parsedOriginData, 0, len(config.RPOrigins))
-	for _, originStr := range config.RPOrigins {
-		u, err := url.Parse(originStr)
-		if err != nil {
-			return nil, fmt.Errorf("%w %s: %w", ErrInvalidRPOrigin, originStr, err)
+	if err := validateAlgorithms(config.Algorithms); err != nil {
+		return nil, err
+	}
+	if config.Compliance != nil {
+		if err := config.Compliance.validate(config.Algorithms); err != nil {
+			return nil, err
 		}
-		if u.Scheme == "" || u.Host == "" {
-			return nil, fmt.Errorf("%w %s: missing scheme or host", ErrInvalidRPOrigin, originStr)
+	}
+
+	parsedOrigins, err := parseOrigins(config.RPOrigins, ErrInvalidRPOrigin)
+	if err != nil {
+		return nil, err
+	}
+	// Related origins live outside RPID by definition, so they are exempt from the check below
+	parsedRelatedOrigins, err := parseOrigins(config.RelatedOrigins, ErrInvalidRelatedOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.SkipOriginRPIDCheck {
+		if err := validateOriginsForRPID(config.RPID, config.RPOrigins); err != nil {
+			return nil, err
 		}
-		parsedOrigins = append(parsedOrigins, parsedOriginData{
-			scheme: strings.ToLower(u.Scheme),
-			host:   strings.ToLower(u.Host),
-		})
+	}
+
+	if config.ConditionalChallenges == nil {
+		config.ConditionalChallenges = NewMemoryChallengeStore(defaultMaxConditionalChallenges)
 	}
 
 	if config.Debug {
-		log.Debug("INFO: WebAuthn debug enabled, config:")
-		log.Debugf("%+v", *config)
+		log.Printf("WebAuthn debug enabled, config: %+v\n", *config)
 	}
 
 	return &WebAuthn{
-		Config:          config,
-		parsedRPOrigins: parsedOrigins,
+		Config:               config,
+		parsedRPOrigins:      parsedOrigins,
+		parsedRelatedOrigins: parsedRelatedOrigins,
 	}, nil
 }
 
-// isAllowedOrigin checks if the configuration allows the provided origin.
-// It compares the scheme and hostname case-insensitively using pre-parsed origins.
+// validateAlgorithms checks that every algorithm is supported and listed once.
+func validateAlgorithms(algorithms []cose.Algorithm) error {
+	seen := make(map[cose.Algorithm]bool, len(algorithms))
+	for _, alg := range algorithms {
+		if !alg.IsValid() {
+			return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+		}
+		if seen[alg] {
+			return fmt.Errorf("%w: %s listed twice", ErrUnsupportedAlgorithm, alg)
+		}
+		seen[alg] = true
+	}
+	return nil
+}
+
+// parseOrigins preparses and normalizes origins, wrapping failures in errInvalid.
+func parseOrigins(origins []string, errInvalid error) ([]parsedOriginData, error) {
+	parsed := make([]parsedOriginData, 0, len(origins))
+	for _, originStr := range origins {
+		u, err := url.Parse(originStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w %s: %w", errInvalid, originStr, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%w %s: missing scheme or host", errInvalid, originStr)
+		}
+		parsed = append(parsed, newParsedOrigin(u))
+	}
+	return parsed, nil
+}
+
+// defaultPorts are the ports dropped from origins, as browsers never serialize them.
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// newParsedOrigin normalizes the scheme and host of an origin: lowercased, and without the scheme's default port.
+func newParsedOrigin(u *url.URL) parsedOriginData {
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Host)
+	if port := u.Port(); port != "" && port == defaultPorts[scheme] {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+	return parsedOriginData{scheme: scheme, host: host}
+}
+
+// validateOriginsForRPID checks that the host of every origin is the RP ID or its subdomain,
+// as browsers would otherwise reject the ceremony with a SecurityError.
+// All offending origins are reported at once.
+func validateOriginsForRPID(rpID string, origins []string) error {
+	rpID = strings.TrimSuffix(strings.ToLower(rpID), ".")
+	var errs []error
+	for _, originStr := range origins {
+		u, err := url.Parse(originStr)
+		if err != nil {
+			return fmt.Errorf("%w %s: %w", ErrInvalidRPOrigin, originStr, err)
+		}
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		if host != rpID && !strings.HasSuffix(host, "."+rpID) {
+			errs = append(errs, fmt.Errorf("%w: origin %s has host %q, which is neither %q nor its subdomain", ErrOriginRPIDMismatch, originStr, host, rpID))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isAllowedOrigin checks if the configuration allows the provided origin, including related origins.
+// It compares the scheme and host case-insensitively, ignoring default ports, using pre-parsed origins.
 func (w *WebAuthn) isAllowedOrigin(origin string) (allowed bool, err error) {
 	receivedURL, err := url.Parse(origin)
 	if err != nil {
 		return false, fmt.Errorf("%w %s: %v", ErrParsingOrigin, origin, err)
 	}
-
-	// Normalize to be safe
-	receivedScheme := strings.ToLower(receivedURL.Scheme)
-	receivedHost := strings.ToLower(receivedURL.Host)
+	received := newParsedOrigin(receivedURL)
 
 	// Check against pre-parsed configured origins
 	for _, parsedOrigin := range w.parsedRPOrigins {
-		if receivedScheme == parsedOrigin.scheme && receivedHost == parsedOrigin.host {
+		if received == parsedOrigin {
+			return true, nil
+		}
+	}
+	// Related origins are allowed to use the same RP ID, so the RP ID hash check still applies
+	for _, parsedOrigin := range w.parsedRelatedOrigins {
+		if received == parsedOrigin {
 			return true, nil
 		}
 	}
@@ -0,0 +1,80 @@
+package webauthn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewRPID(t *testing.T) {
+	tests := []struct {
+		name      string
+		rpID      string
+		origins   []string
+		skipCheck bool
+		wantErr   error
+	}{
+		{"registrable domain", "example.com", []string{"https://example.com"}, false, nil},
+		{"subdomain origin", "example.com", []string{"https://login.example.com:8443"}, false, nil},
+		{"case and trailing dot", "Example.COM.", []string{"https://LOGIN.example.com"}, false, nil},
+		{"subdomain RP ID", "login.example.co.uk", []string{"https://login.example.co.uk"}, false, nil},
+		{"localhost", "localhost", []string{"http://localhost:8080"}, false, nil},
+		{"public suffix", "co.uk", []string{"https://example.co.uk"}, false, ErrRPIDIsPublicSuffix},
+		{"bare TLD", "com", []string{"https://example.com"}, false, ErrRPIDIsPublicSuffix},
+		{"private public suffix", "github.io", []string{"https://user.github.io"}, false, ErrRPIDIsPublicSuffix},
+		{"public suffix, check skipped", "github.io", []string{"https://user.github.io"}, true, ErrRPIDIsPublicSuffix},
+		{"origin outside the RP ID", "example.com", []string{"https://example.org"}, false, ErrOriginRPIDMismatch},
+		{"origin with the RP ID as a label suffix", "example.com", []string{"https://notexample.com"}, false, ErrOriginRPIDMismatch},
+		{"parent of the RP ID", "login.example.com", []string{"https://example.com"}, false, ErrOriginRPIDMismatch},
+		{"one of several origins outside", "example.com", []string{"https://example.com", "https://example.org"}, false, ErrOriginRPIDMismatch},
+		{"origin outside, check skipped", "example.com", []string{"https://example.org"}, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&Config{
+				RPID:                tt.rpID,
+				RPDisplayName:       "Example",
+				RPOrigins:           tt.origins,
+				Timeout:             60000,
+				UserVerification:    UVPreferred,
+				Attestation:         AttestationNone,
+				SkipOriginRPIDCheck: tt.skipCheck,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsAllowedOrigin(t *testing.T) {
+	w, err := New(&Config{
+		RPID:             "example.com",
+		RPDisplayName:    "Example",
+		RPOrigins:        []string{"https://Login.Example.com:443", "http://localhost.example.com:8080"},
+		RelatedOrigins:   []string{"https://example.co.uk"},
+		Timeout:          60000,
+		UserVerification: UVPreferred,
+		Attestation:      AttestationNone,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://login.example.com", true},
+		{"https://LOGIN.example.com:443", true},
+		{"http://localhost.example.com:8080", true},
+		{"https://example.co.uk:443", true},
+		{"http://login.example.com", false},
+		{"https://login.example.com:8443", false},
+		{"http://localhost.example.com", false},
+		{"https://example.com", false},
+	}
+	for _, tt := range tests {
+		if got, _ := w.isAllowedOrigin(tt.origin); got != tt.want {
+			t.Errorf("isAllowedOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}